@@ -5,9 +5,13 @@ go 1.16
 require (
 	github.com/go-kit/kit v0.11.0
 	github.com/go-logfmt/logfmt v0.5.0
+	github.com/go-logr/logr v1.2.4
 	github.com/hashicorp/consul/api v1.8.1
 	github.com/stretchr/testify v1.7.0
 	github.com/xeipuuv/gojsonschema v1.2.0
-	go.uber.org/zap v1.17.0
+	go.opentelemetry.io/otel/trace v1.0.0
+	go.uber.org/multierr v1.6.0
+	go.uber.org/zap v1.21.0
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
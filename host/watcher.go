@@ -25,6 +25,9 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-kits/logger"
 )
 
+// invalidFileLogInterval hostid 文件内容有误时，相同的告警在该周期内只输出一次
+const invalidFileLogInterval = 10 * time.Minute
+
 const (
 	BkCloudIDKey     = "bk_cloud_id"
 	BkHostInnerIPKey = "bk_host_innerip"
@@ -309,7 +312,7 @@ func (w *idWatcher) loopWatch() {
 				// time.C触发后，更新hostid信息
 				err = w.updateInfo()
 				if err != nil {
-					logger.Warnf("update host id failed,err:%s", err.Error())
+					logger.Every(invalidFileLogInterval).Warnw("update host id failed", "error", err)
 				}
 			}
 		}
@@ -361,19 +364,19 @@ func (w *idWatcher) updateInfo() error {
 
 	hostIdInfo, err := w.getHostIdInfoFromFile()
 	if err != nil {
-		logger.Warn("get info from hostid file error: %s", err.Error())
+		logger.Every(invalidFileLogInterval).Warnw("get info from hostid file error", "error", err)
 		return err
 	}
 	bkHostInnerIP, ok := hostIdInfo[BkHostInnerIPKey].(string)
 	if !ok {
-		logger.Warnf("find bk_host_innerip data failed, info value: %v", hostIdInfo)
+		logger.Every(invalidFileLogInterval).Warnw("find bk_host_innerip data failed", "info", hostIdInfo)
 		bkHostInnerIP = ""
 	}
 	w.bkHostInnerIP = bkHostInnerIP
 
 	bkCloudID, ok := hostIdInfo[BkCloudIDKey].(int64)
 	if !ok {
-		logger.Warnf("find bk_cloud_id data failed, info value:%v", hostIdInfo)
+		logger.Every(invalidFileLogInterval).Warnw("find bk_cloud_id data failed", "info", hostIdInfo)
 		bkCloudID = 0
 	}
 	w.bkCloudID = strconv.FormatInt(bkCloudID, 10)
@@ -381,14 +384,14 @@ func (w *idWatcher) updateInfo() error {
 	// 获取associations，这里存放的就是拓扑
 	associations, ok := hostIdInfo[AssociationsKey].(map[string]interface{})
 	if !ok {
-		logger.Warnf("find and convert associations data failed,info value:%v", hostIdInfo)
+		logger.Every(invalidFileLogInterval).Warnw("find and convert associations data failed", "info", hostIdInfo)
 		return ErrGetAssociationFailed
 	}
 
 	// 分析文件，获取cmdb_level
 	topoLinkInfoList, err := w.getInfoFromAssociations(associations)
 	if err != nil {
-		logger.Warnf("get error while anaylize host_id info,info:%s,err:%s", topoLinkInfoList, err.Error())
+		logger.Every(invalidFileLogInterval).Warnw("get error while anaylize host_id info", "info", topoLinkInfoList, "error", err)
 		return err
 	}
 	// 更新使用中的cmdb_level
@@ -0,0 +1,226 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	AsyncBlock      = "block"
+	AsyncDropNewest = "drop_newest"
+	AsyncDropOldest = "drop_oldest"
+
+	defaultAsyncQueueSize     = 4096
+	defaultAsyncFlushInterval = time.Second
+	asyncBufferSize           = 256 * 1024
+)
+
+var errAsyncClosed = errors.New("async writer is closed")
+
+// AsyncOptions is the option set for writing entries asynchronously. Entries
+// are put into a bounded in-memory queue and written by a background goroutine.
+type AsyncOptions struct {
+	// QueueSize is the maximum number of entries waiting to be written, default is 4096.
+	QueueSize int `yaml:"queue_size"`
+
+	// FlushInterval is how often buffered entries are flushed, default is 1s.
+	FlushInterval time.Duration `yaml:"flush_interval"`
+
+	// OnFull is the policy when the queue is full, Valid values are "block",
+	// "drop_newest" and "drop_oldest", default is block.
+	OnFull string `yaml:"on_full"`
+}
+
+// AsyncDropped returns the number of entries dropped by all async sinks of
+// the logger because their queues were full, counted since the last Reload.
+func (l Logger) AsyncDropped() uint64 {
+	var n uint64
+	for _, w := range l.root.load().res.asyncWriters {
+		n += w.Dropped()
+	}
+	return n
+}
+
+// asyncWriter 将写入的内容放入有界队列，由后台 goroutine 批量写入底层 WriteSyncer
+type asyncWriter struct {
+	ws     zapcore.WriteSyncer
+	buf    *bufio.Writer
+	onFull string
+
+	queue         chan []byte
+	syncCh        chan chan error
+	flushInterval time.Duration
+	dropped       uint64
+
+	// mut 保护 closed，Write 持有读锁入队，Close 持有写锁标记关闭，
+	// 保证关闭后不会再有内容入队，后台 goroutine 最后一次 flush 不会漏掉任何一条
+	mut    sync.RWMutex
+	closed bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+func newAsyncWriter(ws zapcore.WriteSyncer, opt AsyncOptions) (*asyncWriter, error) {
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = defaultAsyncQueueSize
+	}
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = defaultAsyncFlushInterval
+	}
+	switch opt.OnFull {
+	case "":
+		opt.OnFull = AsyncBlock
+	case AsyncBlock, AsyncDropNewest, AsyncDropOldest:
+	default:
+		return nil, fmt.Errorf("unknown async on_full policy: %q", opt.OnFull)
+	}
+
+	w := &asyncWriter{
+		ws:            ws,
+		buf:           bufio.NewWriterSize(ws, asyncBufferSize),
+		onFull:        opt.OnFull,
+		queue:         make(chan []byte, opt.QueueSize),
+		syncCh:        make(chan chan error),
+		flushInterval: opt.FlushInterval,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Write implements io.Writer, p is copied since zap reuses the buffer
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mut.RLock()
+	defer w.mut.RUnlock()
+	if w.closed {
+		return 0, errAsyncClosed
+	}
+
+	b := make([]byte, len(p))
+	copy(b, p)
+
+	switch w.onFull {
+	case AsyncDropNewest:
+		select {
+		case w.queue <- b:
+		default:
+			atomic.AddUint64(&w.dropped, 1)
+		}
+
+	case AsyncDropOldest:
+		for {
+			select {
+			case w.queue <- b:
+				return len(p), nil
+			default:
+			}
+			// 队列已满，丢弃最旧的一条后重试
+			select {
+			case <-w.queue:
+				atomic.AddUint64(&w.dropped, 1)
+			default:
+			}
+		}
+
+	default:
+		// 后台 goroutine 在 Close 取得写锁之前一直运行，阻塞的写入总能完成
+		w.queue <- b
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer, it blocks until all queued entries are
+// written and synced.
+func (w *asyncWriter) Sync() error {
+	reply := make(chan error, 1)
+	select {
+	case w.syncCh <- reply:
+		return <-reply
+	case <-w.stopped:
+		return nil
+	}
+}
+
+// Close drains the queue and stops the background goroutine.
+func (w *asyncWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.mut.Lock()
+		w.closed = true
+		w.mut.Unlock()
+		close(w.done)
+	})
+	<-w.stopped
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (w *asyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case b := <-w.queue:
+			w.buf.Write(b)
+		case <-ticker.C:
+			w.flushBuffer()
+		case reply := <-w.syncCh:
+			reply <- w.flush()
+		case <-w.done:
+			w.flush()
+			return
+		}
+	}
+}
+
+// flush 写入队列中剩余的内容并同步到底层
+func (w *asyncWriter) flush() error {
+	for {
+		select {
+		case b := <-w.queue:
+			w.buf.Write(b)
+			continue
+		default:
+		}
+		break
+	}
+
+	if err := w.flushBuffer(); err != nil {
+		return err
+	}
+	return w.ws.Sync()
+}
+
+// flushBuffer 写入失败时丢弃缓冲区，避免 bufio.Writer 永久处于错误状态
+func (w *asyncWriter) flushBuffer() error {
+	err := w.buf.Flush()
+	if err != nil {
+		w.buf.Reset(w.ws)
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+// blockingWriter 在 release 关闭前阻塞所有写入，模拟慢磁盘，进入 Write 时通知 entered
+type blockingWriter struct {
+	syncBuffer
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.entered <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return w.syncBuffer.Write(p)
+}
+
+func (w *blockingWriter) Sync() error { return nil }
+
+func TestAsyncWriterDrain(t *testing.T) {
+	buf := new(syncBuffer)
+	w, err := newAsyncWriter(zapcore.AddSync(buf), AsyncOptions{FlushInterval: time.Hour})
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	assert.NoError(t, w.Sync())
+	assert.Equal(t, 100, strings.Count(buf.String(), "\n"))
+
+	fmt.Fprintln(w, "last")
+	assert.NoError(t, w.Close())
+	assert.True(t, strings.HasSuffix(buf.String(), "last\n"))
+
+	_, err = w.Write([]byte("closed\n"))
+	assert.Equal(t, errAsyncClosed, err)
+}
+
+func TestAsyncWriterDropPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy string
+		want   string
+	}{
+		{AsyncDropNewest, "line 0\nline 1\nline 2\n"},
+		{AsyncDropOldest, "line 0\nline 8\nline 9\n"},
+	} {
+		bw := &blockingWriter{entered: make(chan struct{}, 1), release: make(chan struct{})}
+		w, err := newAsyncWriter(bw, AsyncOptions{QueueSize: 2, FlushInterval: time.Hour, OnFull: tc.policy})
+		assert.NoError(t, err)
+
+		// 第一条随 Sync 写入底层时阻塞后台 goroutine，之后的内容只能进入容量为 2 的队列或被丢弃
+		fmt.Fprintln(w, "line 0")
+		synced := make(chan error, 1)
+		go func() { synced <- w.Sync() }()
+		select {
+		case <-bw.entered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("background goroutine did not flush")
+		}
+
+		for i := 1; i < 10; i++ {
+			_, err := fmt.Fprintf(w, "line %d\n", i)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, uint64(7), w.Dropped(), tc.policy)
+
+		close(bw.release)
+		assert.NoError(t, <-synced)
+		assert.NoError(t, w.Close())
+		assert.Equal(t, tc.want, bw.String(), tc.policy)
+	}
+}
+
+func TestAsyncWriterCloseRace(t *testing.T) {
+	for _, policy := range []string{AsyncBlock, AsyncDropNewest, AsyncDropOldest} {
+		buf := new(syncBuffer)
+		w, err := newAsyncWriter(zapcore.AddSync(buf), AsyncOptions{QueueSize: 16, OnFull: policy})
+		assert.NoError(t, err)
+
+		// 与 Close 并发的写入要么返回错误，要么被写入或计入丢弃，不会静默丢失
+		var wg sync.WaitGroup
+		var accepted uint64
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					if _, err := w.Write([]byte("line\n")); err != nil {
+						return
+					}
+					atomic.AddUint64(&accepted, 1)
+				}
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, w.Close())
+		wg.Wait()
+
+		written := uint64(strings.Count(buf.String(), "\n"))
+		assert.Equal(t, accepted, written+w.Dropped(), policy)
+	}
+}
+
+func TestLoggerAsync(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "applog")
+	l := New(Options{Filename: filename, Async: &AsyncOptions{FlushInterval: time.Hour}})
+
+	l.Info("async message")
+	assert.NoError(t, l.sugared.Sync())
+
+	b, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `msg="async message"`)
+	assert.Equal(t, uint64(0), l.AsyncDropped())
+}
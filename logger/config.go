@@ -0,0 +1,301 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/multierr"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadOptions reads Options from the YAML file at path. Environment
+// overrides are not applied, call ApplyEnv on the result if needed.
+func LoadOptions(path string) (Options, error) {
+	var opt Options
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return opt, err
+	}
+	if err := yaml.Unmarshal(b, &opt); err != nil {
+		return opt, fmt.Errorf("parse logger options %s: %w", path, err)
+	}
+	return opt, nil
+}
+
+// OptionsFromEnv returns Options built from the environment variables with
+// the given prefix. See ApplyEnv for the naming of the variables.
+func OptionsFromEnv(prefix string) (Options, error) {
+	var opt Options
+	err := opt.ApplyEnv(prefix)
+	return opt, err
+}
+
+// ApplyEnv overrides the fields of opt with environment variables. A
+// variable is named by the prefix and the upper-cased yaml key, e.g.
+// BK_LOG_LEVEL and BK_LOG_MAX_SIZE for prefix "BK_LOG", and
+// BK_LOG_SAMPLING_TICK for nested options. Levels is written as
+// "host=warn,register.consul=debug". Sinks can only be set from a file.
+func (opt *Options) ApplyEnv(prefix string) error {
+	return applyEnv(reflect.ValueOf(opt).Elem(), strings.TrimSuffix(prefix, "_"))
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv 按照 yaml tag 递归设置字段，指针类型的结构体只在存在对应变量时才分配
+func applyEnv(v reflect.Value, prefix string) error {
+	var errs error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := strings.ToUpper(tag)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		field := v.Field(i)
+		if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct {
+			if !hasEnvPrefix(name + "_") {
+				continue
+			}
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			errs = multierr.Append(errs, applyEnv(field.Elem(), name))
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(field, value); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errs
+}
+
+func hasEnvPrefix(prefix string) bool {
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func setEnvValue(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Map && field.Type().Key().Kind() == reflect.String && field.Type().Elem().Kind() == reflect.String:
+		m := make(map[string]string)
+		for _, pair := range strings.Split(value, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid key=value pair: %q", pair)
+			}
+			m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+		field.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported from environment")
+	}
+	return nil
+}
+
+// Validate checks the levels, formats, rotation and compression settings
+// and whether the log files are writable. All problems found are reported
+// together in the returned error.
+func (opt Options) Validate() error {
+	var errs error
+	if err := validateLevel("level", opt.Level); err != nil {
+		errs = multierr.Append(errs, err)
+	}
+	for name, level := range opt.Levels {
+		if err := validateLevel(fmt.Sprintf("levels[%s]", name), level); err != nil {
+			errs = multierr.Append(errs, err)
+		}
+	}
+	if err := validateFormat("format", opt.Format); err != nil {
+		errs = multierr.Append(errs, err)
+	}
+
+	for i, sink := range opt.sinks() {
+		// 未配置 Sinks 时字段来自 Options 本身，错误信息中不加 sinks 前缀
+		prefix := ""
+		if len(opt.Sinks) > 0 {
+			prefix = fmt.Sprintf("sinks[%d].", i)
+		}
+		errs = multierr.Append(errs, sink.validate(prefix))
+	}
+
+	if opt.Redact != nil {
+		if _, err := newRedactor(*opt.Redact); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("redact: %w", err))
+		}
+	}
+	for i, hook := range opt.Hooks {
+		if hook.Fire == nil {
+			errs = multierr.Append(errs, fmt.Errorf("hooks[%d]: Fire is nil", i))
+		}
+	}
+	if opt.Async != nil {
+		switch opt.Async.OnFull {
+		case "", AsyncBlock, AsyncDropNewest, AsyncDropOldest:
+		default:
+			errs = multierr.Append(errs, fmt.Errorf("async.on_full: unknown policy %q", opt.Async.OnFull))
+		}
+	}
+	return errs
+}
+
+func (sink SinkOptions) validate(prefix string) error {
+	var errs error
+	if sink.Level != "" {
+		errs = multierr.Append(errs, validateLevel(prefix+"level", sink.Level))
+	}
+	errs = multierr.Append(errs, validateFormat(prefix+"format", sink.Format))
+
+	switch sink.Type {
+	case SinkStdout, SinkStderr:
+		return errs
+	case SinkSyslog:
+		return multierr.Append(errs, sink.Syslog.validate(prefix))
+	case SinkNet:
+		return multierr.Append(errs, sink.Net.validate(prefix))
+	case SinkFile, "":
+	default:
+		return multierr.Append(errs, fmt.Errorf("%stype: unknown sink type %q", prefix, sink.Type))
+	}
+
+	if _, ok := defaultRotationPatterns[sink.Rotation]; !ok {
+		errs = multierr.Append(errs, fmt.Errorf("%srotation: unknown rotation %q", prefix, sink.Rotation))
+	}
+	switch sink.Compress {
+	case "", CompressGzip:
+	default:
+		errs = multierr.Append(errs, fmt.Errorf("%scompress: unknown compress %q", prefix, sink.Compress))
+	}
+	if sink.DisableRotation && (sink.Rotation != "" || sink.Compress != "") {
+		errs = multierr.Append(errs, fmt.Errorf("%sdisable_rotation: conflicts with rotation and compress", prefix))
+	}
+	if sink.DisableRotation && sink.Filename == "" {
+		errs = multierr.Append(errs, fmt.Errorf("%sfilename: required when rotation is disabled", prefix))
+	}
+	if sink.CompressLevel != 0 {
+		if _, err := gzip.NewWriterLevel(ioutil.Discard, sink.CompressLevel); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("%scompress_level: %w", prefix, err))
+		}
+	}
+	if sink.Filename != "" {
+		if err := checkWritable(sink.Filename); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("%sfilename: %w", prefix, err))
+		}
+	}
+	return errs
+}
+
+func validateLevel(name, level string) error {
+	if level == "" {
+		return nil
+	}
+	if _, err := ParseLevel(level); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func validateFormat(name, format string) error {
+	switch format {
+	case "", "json", "console", "logfmt":
+		return nil
+	}
+	return fmt.Errorf("%s: unknown format %q", name, format)
+}
+
+// checkWritable 检查日志文件是否可写，文件不存在时检查最近一级已存在的目录，不会创建缺失的目录
+func checkWritable(filename string) error {
+	info, err := os.Stat(filename)
+	if err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", filename)
+		}
+		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	dir := filepath.Dir(filename)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	f, err := ioutil.TempFile(dir, ".bklog-check-")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
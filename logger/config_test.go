@@ -0,0 +1,143 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logger.yaml")
+	content := `
+level: warn
+format: json
+levels:
+  host: error
+sampling:
+  tick: 2s
+  initial: 10
+sinks:
+  - type: stdout
+  - type: file
+    filename: /data/log/app.log
+    level: error
+    rotation: daily
+`
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	opt, err := LoadOptions(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "warn", opt.Level)
+	assert.Equal(t, "json", opt.Format)
+	assert.Equal(t, map[string]string{"host": "error"}, opt.Levels)
+	assert.Equal(t, &SamplingOptions{Tick: 2 * time.Second, Initial: 10}, opt.Sampling)
+	assert.Equal(t, []SinkOptions{
+		{Type: SinkStdout},
+		{Type: SinkFile, Filename: "/data/log/app.log", Level: "error", Rotation: RotationDaily},
+	}, opt.Sinks)
+
+	_, err = LoadOptions(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	env := map[string]string{
+		"BK_LOG_LEVEL":          "debug",
+		"BK_LOG_STDOUT":         "true",
+		"BK_LOG_MAX_SIZE":       "100",
+		"BK_LOG_LEVELS":         "host=warn, register.consul=debug",
+		"BK_LOG_ASYNC_ON_FULL":  "drop_newest",
+		"BK_LOG_SAMPLING_TICK":  "5s",
+		"BK_LOGGER_LEVEL":       "error",
+		"BK_LOG_SAMPLING_OTHER": "ignored",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	opt, err := OptionsFromEnv("BK_LOG")
+	assert.NoError(t, err)
+	assert.Equal(t, "debug", opt.Level)
+	assert.True(t, opt.Stdout)
+	assert.Equal(t, 100, opt.MaxSize)
+	assert.Equal(t, map[string]string{"host": "warn", "register.consul": "debug"}, opt.Levels)
+	assert.Equal(t, &AsyncOptions{OnFull: AsyncDropNewest}, opt.Async)
+	assert.Equal(t, &SamplingOptions{Tick: 5 * time.Second}, opt.Sampling)
+
+	// 环境变量覆盖文件中的配置
+	opt = Options{Level: "info", Format: "json"}
+	assert.NoError(t, opt.ApplyEnv("BK_LOG_"))
+	assert.Equal(t, "debug", opt.Level)
+	assert.Equal(t, "json", opt.Format)
+
+	os.Setenv("BK_LOG_MAX_SIZE", "large")
+	_, err = OptionsFromEnv("BK_LOG")
+	assert.EqualError(t, err, `BK_LOG_MAX_SIZE: strconv.Atoi: parsing "large": invalid syntax`)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, Options{Stdout: true}.Validate())
+	assert.NoError(t, Options{Filename: filepath.Join(dir, "a", "b", "app.log"), Level: "WARN"}.Validate())
+
+	err := Options{
+		Level:  "verbose",
+		Format: "xml",
+		Levels: map[string]string{"host": "loud"},
+		Sinks: []SinkOptions{
+			{Type: SinkStdout, Format: "yaml"},
+			{Type: "kafka"},
+			{Type: SinkFile, Filename: dir, Rotation: "weekly", Compress: "zstd"},
+		},
+		Async: &AsyncOptions{OnFull: "wait"},
+	}.Validate()
+	assert.EqualError(t, err, `level: unrecognized level: "verbose"; `+
+		`levels[host]: unrecognized level: "loud"; `+
+		`format: unknown format "xml"; `+
+		`sinks[0].format: unknown format "yaml"; `+
+		`sinks[1].type: unknown sink type "kafka"; `+
+		`sinks[2].rotation: unknown rotation "weekly"; `+
+		`sinks[2].compress: unknown compress "zstd"; `+
+		`sinks[2].filename: `+dir+` is a directory; `+
+		`async.on_full: unknown policy "wait"`)
+}
+
+func TestOptionsValidateUnwritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	dir := t.TempDir()
+	assert.NoError(t, os.Chmod(dir, 0555))
+	defer os.Chmod(dir, 0755)
+
+	err := Options{Filename: filepath.Join(dir, "logs", "app.log")}.Validate()
+	assert.Error(t, err)
+}
+
+func TestBuild(t *testing.T) {
+	_, err := Build(Options{Level: "verbose", Stdout: true})
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		New(Options{Level: "verbose", Stdout: true})
+	})
+
+	l, err := Build(Options{Level: "debug", Filename: filepath.Join(t.TempDir(), "app.log")})
+	assert.NoError(t, err)
+	assert.Equal(t, DebugLevel, l.GetLevel())
+	assert.NoError(t, l.Close())
+}
@@ -0,0 +1,172 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	connDialTimeout  = 5 * time.Second
+	connWriteTimeout = 5 * time.Second
+	connMinBackoff   = 100 * time.Millisecond
+	connMaxBackoff   = 30 * time.Second
+)
+
+var errConnUnavailable = errors.New("connection is unavailable, waiting to reconnect")
+
+// redialConn 维护到远端的连接，写入失败时断开并按指数退避重连，退避期间的写入直接返回 errConnUnavailable。
+// 只有第一次在调用方同步建立连接，保证启动时的日志不会丢失；之后的重连在后台进行，写入不会阻塞在建立连接上
+type redialConn struct {
+	dial func() (net.Conn, error)
+
+	mut        sync.Mutex
+	conn       net.Conn
+	stream     bool // 当前连接是否为流式连接，只有流式连接写入时设置超时
+	dialed     bool // 是否已经尝试过建立连接
+	dialing    bool // 后台正在建立连接
+	closed     bool
+	backoff    time.Duration
+	nextDial   time.Time
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	now        func() time.Time
+}
+
+func newRedialConn(network, address string) *redialConn {
+	return newRedialConnFunc(func() (net.Conn, error) {
+		return net.DialTimeout(network, address, connDialTimeout)
+	})
+}
+
+func newRedialConnFunc(dial func() (net.Conn, error)) *redialConn {
+	return &redialConn{
+		dial:       dial,
+		minBackoff: connMinBackoff,
+		maxBackoff: connMaxBackoff,
+		now:        time.Now,
+	}
+}
+
+// Write implements io.Writer, p is written to the current connection as is.
+// It returns errConnUnavailable while reconnecting and os.ErrClosed after Close.
+func (c *redialConn) Write(p []byte) (int, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.closed {
+		return 0, os.ErrClosed
+	}
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return 0, err
+		}
+	}
+
+	if c.stream {
+		c.conn.SetWriteDeadline(c.now().Add(connWriteTimeout))
+	}
+	n, err := c.conn.Write(p)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		c.fail()
+		return n, err
+	}
+	c.backoff = 0
+	return n, nil
+}
+
+// Sync implements zapcore.WriteSyncer
+func (c *redialConn) Sync() error {
+	return nil
+}
+
+// Close implements io.Closer, a connection dialed in the background after
+// Close is closed at once.
+func (c *redialConn) Close() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// connect 第一次同步建立连接，之后在退避时间过后由后台 goroutine 重连，同一时间只有一个在重连
+func (c *redialConn) connect() error {
+	if c.dialing || c.now().Before(c.nextDial) {
+		return errConnUnavailable
+	}
+	if c.dialed {
+		c.dialing = true
+		go c.redial()
+		return errConnUnavailable
+	}
+
+	c.dialed = true
+	conn, err := c.dial()
+	if err != nil {
+		c.fail()
+		return err
+	}
+	c.setConn(conn)
+	return nil
+}
+
+func (c *redialConn) redial() {
+	conn, err := c.dial()
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.dialing = false
+	switch {
+	case err != nil:
+		c.fail()
+	case c.closed:
+		conn.Close()
+	default:
+		c.setConn(conn)
+	}
+}
+
+// setConn 根据实际建立的连接判断是否为流式连接，本地 syslog 可能是 unixgram 也可能是 unix
+func (c *redialConn) setConn(conn net.Conn) {
+	c.conn = conn
+	c.stream = false
+	if addr := conn.RemoteAddr(); addr != nil {
+		switch addr.Network() {
+		case "tcp", "unix", "unixpacket":
+			c.stream = true
+		}
+	}
+}
+
+// fail 记录一次失败，下一次建立连接的时间按指数退避
+func (c *redialConn) fail() {
+	if c.backoff == 0 {
+		c.backoff = c.minBackoff
+	} else {
+		c.backoff *= 2
+	}
+	if c.backoff > c.maxBackoff {
+		c.backoff = c.maxBackoff
+	}
+	c.nextDial = c.now().Add(c.backoff)
+}
@@ -0,0 +1,262 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceIDKey is the field key of the trace id attached by context-aware logging.
+	TraceIDKey = "trace_id"
+
+	// SpanIDKey is the field key of the span id attached by context-aware logging.
+	SpanIDKey = "span_id"
+)
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+type (
+	loggerCtxKey      struct{}
+	fieldsCtxKey      struct{}
+	traceparentCtxKey struct{}
+)
+
+type traceparent struct {
+	traceID string
+	spanID  string
+}
+
+// NewContext returns a copy of ctx carrying l, which is then used by
+// FromContext and the package-level context-aware functions.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, l)
+}
+
+// ContextWithFields returns a copy of ctx carrying the given fields. They are
+// appended to the fields already stored in ctx and attached to every entry
+// logged with the context. The arguments are treated as they are in With.
+func ContextWithFields(ctx context.Context, args ...interface{}) context.Context {
+	prev, _ := ctx.Value(fieldsCtxKey{}).([]interface{})
+	fields := make([]interface{}, 0, len(prev)+len(args))
+	fields = append(fields, prev...)
+	fields = append(fields, args...)
+	return context.WithValue(ctx, fieldsCtxKey{}, fields)
+}
+
+// ContextWithTraceparent returns a copy of ctx carrying the trace and span id
+// parsed from a W3C traceparent header, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01". An OpenTelemetry
+// span context stored in ctx takes precedence over it.
+func ContextWithTraceparent(ctx context.Context, header string) (context.Context, error) {
+	tp, err := parseTraceparent(header)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, traceparentCtxKey{}, tp), nil
+}
+
+// parseTraceparent 解析 version-traceid-spanid-flags 格式的 traceparent
+func parseTraceparent(header string) (traceparent, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return traceparent{}, ErrInvalidTraceparent
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	// 版本 00 只允许 4 段，ff 为非法版本
+	if version == "ff" || (version == "00" && len(parts) != 4) {
+		return traceparent{}, ErrInvalidTraceparent
+	}
+	if !isHex(version, 2) || !isHex(traceID, 32) || !isHex(spanID, 16) || !isHex(flags, 2) {
+		return traceparent{}, ErrInvalidTraceparent
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return traceparent{}, ErrInvalidTraceparent
+	}
+	return traceparent{traceID: traceID, spanID: spanID}, nil
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// contextFields 返回 ctx 中需要附加到日志的字段
+func contextFields(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+
+	fields, _ := ctx.Value(fieldsCtxKey{}).([]interface{})
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return append(fields[:len(fields):len(fields)], TraceIDKey, sc.TraceID().String(), SpanIDKey, sc.SpanID().String())
+	}
+	if tp, ok := ctx.Value(traceparentCtxKey{}).(traceparent); ok {
+		return append(fields[:len(fields):len(fields)], TraceIDKey, tp.traceID, SpanIDKey, tp.spanID)
+	}
+	return fields
+}
+
+// WithContext returns a logger with the fields and trace information carried
+// by ctx attached.
+func (l Logger) WithContext(ctx context.Context) Logger {
+	if fields := contextFields(ctx); len(fields) > 0 {
+		return l.With(fields...)
+	}
+	return l
+}
+
+// WithContext returns the standard logger with the fields and trace
+// information carried by ctx attached.
+func WithContext(ctx context.Context) Logger {
+	return std.WithContext(ctx)
+}
+
+// FromContext returns the logger stored in ctx by NewContext, or the standard
+// logger if there is none, with the fields and trace information carried by
+// ctx attached.
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerCtxKey{}).(Logger); ok {
+			return l.WithContext(ctx)
+		}
+	}
+	return std.WithContext(ctx)
+}
+
+// Debugc uses fmt.Sprint to construct and log a message with the context.
+func (l Logger) Debugc(ctx context.Context, args ...interface{}) {
+	l.WithContext(ctx).sugared.Debug(args...)
+}
+
+// Infoc uses fmt.Sprint to construct and log a message with the context.
+func (l Logger) Infoc(ctx context.Context, args ...interface{}) {
+	l.WithContext(ctx).sugared.Info(args...)
+}
+
+// Warnc uses fmt.Sprint to construct and log a message with the context.
+func (l Logger) Warnc(ctx context.Context, args ...interface{}) {
+	l.WithContext(ctx).sugared.Warn(args...)
+}
+
+// Errorc uses fmt.Sprint to construct and log a message with the context.
+func (l Logger) Errorc(ctx context.Context, args ...interface{}) {
+	l.WithContext(ctx).sugared.Error(args...)
+}
+
+// Debugcf uses fmt.Sprintf to log a templated message with the context.
+func (l Logger) Debugcf(ctx context.Context, template string, args ...interface{}) {
+	l.WithContext(ctx).sugared.Debugf(template, args...)
+}
+
+// Infocf uses fmt.Sprintf to log a templated message with the context.
+func (l Logger) Infocf(ctx context.Context, template string, args ...interface{}) {
+	l.WithContext(ctx).sugared.Infof(template, args...)
+}
+
+// Warncf uses fmt.Sprintf to log a templated message with the context.
+func (l Logger) Warncf(ctx context.Context, template string, args ...interface{}) {
+	l.WithContext(ctx).sugared.Warnf(template, args...)
+}
+
+// Errorcf uses fmt.Sprintf to log a templated message with the context.
+func (l Logger) Errorcf(ctx context.Context, template string, args ...interface{}) {
+	l.WithContext(ctx).sugared.Errorf(template, args...)
+}
+
+// Debugcw logs a message with the context and some additional key-value pairs.
+func (l Logger) Debugcw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.WithContext(ctx).sugared.Debugw(msg, keysAndValues...)
+}
+
+// Infocw logs a message with the context and some additional key-value pairs.
+func (l Logger) Infocw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.WithContext(ctx).sugared.Infow(msg, keysAndValues...)
+}
+
+// Warncw logs a message with the context and some additional key-value pairs.
+func (l Logger) Warncw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.WithContext(ctx).sugared.Warnw(msg, keysAndValues...)
+}
+
+// Errorcw logs a message with the context and some additional key-value pairs.
+func (l Logger) Errorcw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.WithContext(ctx).sugared.Errorw(msg, keysAndValues...)
+}
+
+// Debugc uses fmt.Sprint to construct and log a message with the context.
+func Debugc(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).sugared.Debug(args...)
+}
+
+// Infoc uses fmt.Sprint to construct and log a message with the context.
+func Infoc(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).sugared.Info(args...)
+}
+
+// Warnc uses fmt.Sprint to construct and log a message with the context.
+func Warnc(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).sugared.Warn(args...)
+}
+
+// Errorc uses fmt.Sprint to construct and log a message with the context.
+func Errorc(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).sugared.Error(args...)
+}
+
+// Debugcf uses fmt.Sprintf to log a templated message with the context.
+func Debugcf(ctx context.Context, template string, args ...interface{}) {
+	FromContext(ctx).sugared.Debugf(template, args...)
+}
+
+// Infocf uses fmt.Sprintf to log a templated message with the context.
+func Infocf(ctx context.Context, template string, args ...interface{}) {
+	FromContext(ctx).sugared.Infof(template, args...)
+}
+
+// Warncf uses fmt.Sprintf to log a templated message with the context.
+func Warncf(ctx context.Context, template string, args ...interface{}) {
+	FromContext(ctx).sugared.Warnf(template, args...)
+}
+
+// Errorcf uses fmt.Sprintf to log a templated message with the context.
+func Errorcf(ctx context.Context, template string, args ...interface{}) {
+	FromContext(ctx).sugared.Errorf(template, args...)
+}
+
+// Debugcw logs a message with the context and some additional key-value pairs.
+func Debugcw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).sugared.Debugw(msg, keysAndValues...)
+}
+
+// Infocw logs a message with the context and some additional key-value pairs.
+func Infocw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).sugared.Infow(msg, keysAndValues...)
+}
+
+// Warncw logs a message with the context and some additional key-value pairs.
+func Warncw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).sugared.Warnw(msg, keysAndValues...)
+}
+
+// Errorcw logs a message with the context and some additional key-value pairs.
+func Errorcw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).sugared.Errorw(msg, keysAndValues...)
+}
@@ -0,0 +1,77 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextFields(t *testing.T) {
+	l, buf := newBufferLogger(DebugLevel)
+
+	ctx := ContextWithFields(context.Background(), "request_id", "r-1")
+	ctx = ContextWithFields(ctx, "user", "admin")
+	l.Infoc(ctx, "hello")
+	assert.Equal(t, "level=info msg=hello request_id=r-1 user=admin\n", buf.String())
+
+	buf.Reset()
+	l.Warncw(context.Background(), "no fields", "k", "v")
+	assert.Equal(t, "level=warn msg=\"no fields\" k=v\n", buf.String())
+}
+
+func TestContextTraceparent(t *testing.T) {
+	l, buf := newBufferLogger(DebugLevel)
+
+	ctx, err := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NoError(t, err)
+	l.Infocf(ctx, "hello %s", "world")
+	assert.Equal(t, "level=info msg=\"hello world\" trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7\n", buf.String())
+
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, err := ContextWithTraceparent(context.Background(), header)
+		assert.Equal(t, ErrInvalidTraceparent, err, header)
+	}
+}
+
+func TestContextSpanContext(t *testing.T) {
+	l, buf := newBufferLogger(DebugLevel)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	// OpenTelemetry 的 span context 优先于 traceparent
+	ctx, _ := ContextWithTraceparent(context.Background(), "00-11111111111111111111111111111111-2222222222222222-01")
+	ctx = trace.ContextWithSpanContext(ctx, sc)
+	l.Errorc(ctx, "failed")
+	assert.Equal(t, "level=error msg=failed trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7\n", buf.String())
+}
+
+func TestFromContext(t *testing.T) {
+	l, buf := newBufferLogger(DebugLevel)
+
+	ctx := NewContext(context.Background(), l.With("component", "api"))
+	ctx = ContextWithFields(ctx, "request_id", "r-1")
+	Infoc(ctx, "hello")
+	FromContext(ctx).Debug("world")
+	assert.Equal(t, "level=info msg=hello component=api request_id=r-1\nlevel=debug msg=world component=api request_id=r-1\n", buf.String())
+}
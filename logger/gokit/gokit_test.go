@@ -18,6 +18,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/TencentBlueKing/bkmonitor-kits/logger"
+	"github.com/TencentBlueKing/bkmonitor-kits/logger/loggertest"
 )
 
 func TestGoKitLogger(t *testing.T) {
@@ -34,3 +35,17 @@ func TestGoKitLogger(t *testing.T) {
 	assert.Empty(t, level.Warn(WithValKitLog).Log("msg", "debug_msg", "missing"))
 	assert.Empty(t, level.Info(WithValKitLog).Log("msg", "exiting"))
 }
+
+func TestGoKitLoggerEntries(t *testing.T) {
+	l, logs := loggertest.New()
+	kitLog := NewLogger(l)
+
+	assert.NoError(t, level.Debug(kitLog).Log("msg", "debug_msg", "missing"))
+	assert.NoError(t, log.With(kitLog, "component", "api").Log("msg", "world"))
+	assert.NoError(t, level.Error(kitLog).Log("msg", "failed", "code", 500))
+
+	logs.AssertMessages(t, "debug_msg", "world", "failed")
+	logs.AssertLogged(t, logger.DebugLevel, "debug_msg", "missing", log.ErrMissingValue)
+	logs.AssertLogged(t, logger.InfoLevel, "world", "component", "api")
+	logs.AssertLogged(t, logger.ErrorLevel, "failed", "code", 500)
+}
@@ -0,0 +1,186 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+// Package grpclog adapts logger.Logger to google.golang.org/grpc/grpclog, so
+// that the messages of grpc-go go to the kits logger instead of stderr:
+//
+//	bkgrpclog.SetLogger(logger.StandardLogger(), bkgrpclog.Options{DowngradeInfo: true})
+package grpclog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/grpclog"
+
+	"github.com/TencentBlueKing/bkmonitor-kits/logger"
+)
+
+// LoggerName is the name of the logger grpc messages are written to, the
+// level can be tuned with Options.Levels, e.g. {"grpc": "warn"}.
+const LoggerName = "grpc"
+
+// grpclog 的包级函数和 component logger 都会增加一层调用
+const grpclogDepth = 2
+
+// Options is the option set for Logger.
+type Options struct {
+	// DowngradeInfo writes the info messages of grpc, which are mostly about
+	// connectivity changes, at debug level.
+	DowngradeInfo bool
+}
+
+// Logger implements grpclog.LoggerV2 and grpclog.DepthLoggerV2 on top of
+// logger.Logger. Verbose messages guarded by V(l) with l > 0 are only
+// enabled when the logger is at debug level. Fatal messages exit the
+// process after the sinks are closed.
+type Logger struct {
+	logger    *zap.Logger
+	infoLevel zapcore.Level
+
+	// depths 按调用深度缓存跳过对应层数的 logger，grpc 使用的深度只有少数几种
+	depths sync.Map // int -> *zap.Logger
+}
+
+var _ grpclog.DepthLoggerV2 = (*Logger)(nil)
+
+// NewLogger returns a grpc logger writing to l named "grpc".
+func NewLogger(l logger.Logger, opt Options) *Logger {
+	g := &Logger{
+		// 跳过 Logger 自身的 log 方法
+		logger:    l.Named(LoggerName).Desugar().WithOptions(zap.AddCallerSkip(1)),
+		infoLevel: zapcore.InfoLevel,
+	}
+	if opt.DowngradeInfo {
+		g.infoLevel = zapcore.DebugLevel
+	}
+	return g
+}
+
+// SetLogger makes grpc write through l. Like grpclog.SetLoggerV2, it must
+// be called before any grpc function and is not goroutine safe.
+func SetLogger(l logger.Logger, opt Options) {
+	grpclog.SetLoggerV2(NewLogger(l, opt))
+}
+
+// log 在级别未开启时直接返回，避免为被丢弃的日志构造 logger
+func (g *Logger) log(depth int, level zapcore.Level, msg string) {
+	if !g.logger.Core().Enabled(level) {
+		return
+	}
+	if ce := g.depthLogger(depth).Check(level, msg); ce != nil {
+		ce.Write()
+	}
+}
+
+func (g *Logger) depthLogger(depth int) *zap.Logger {
+	if l, ok := g.depths.Load(depth); ok {
+		return l.(*zap.Logger)
+	}
+	l, _ := g.depths.LoadOrStore(depth, g.logger.WithOptions(zap.AddCallerSkip(depth)))
+	return l.(*zap.Logger)
+}
+
+// Info implements grpclog.LoggerV2.
+func (g *Logger) Info(args ...interface{}) {
+	g.log(grpclogDepth, g.infoLevel, fmt.Sprint(args...))
+}
+
+// Infoln implements grpclog.LoggerV2.
+func (g *Logger) Infoln(args ...interface{}) {
+	g.log(grpclogDepth, g.infoLevel, sprintln(args))
+}
+
+// Infof implements grpclog.LoggerV2.
+func (g *Logger) Infof(format string, args ...interface{}) {
+	g.log(grpclogDepth, g.infoLevel, fmt.Sprintf(format, args...))
+}
+
+// InfoDepth implements grpclog.DepthLoggerV2.
+func (g *Logger) InfoDepth(depth int, args ...interface{}) {
+	g.log(depth+grpclogDepth, g.infoLevel, sprintln(args))
+}
+
+// Warning implements grpclog.LoggerV2.
+func (g *Logger) Warning(args ...interface{}) {
+	g.log(grpclogDepth, zapcore.WarnLevel, fmt.Sprint(args...))
+}
+
+// Warningln implements grpclog.LoggerV2.
+func (g *Logger) Warningln(args ...interface{}) {
+	g.log(grpclogDepth, zapcore.WarnLevel, sprintln(args))
+}
+
+// Warningf implements grpclog.LoggerV2.
+func (g *Logger) Warningf(format string, args ...interface{}) {
+	g.log(grpclogDepth, zapcore.WarnLevel, fmt.Sprintf(format, args...))
+}
+
+// WarningDepth implements grpclog.DepthLoggerV2.
+func (g *Logger) WarningDepth(depth int, args ...interface{}) {
+	g.log(depth+grpclogDepth, zapcore.WarnLevel, sprintln(args))
+}
+
+// Error implements grpclog.LoggerV2.
+func (g *Logger) Error(args ...interface{}) {
+	g.log(grpclogDepth, zapcore.ErrorLevel, fmt.Sprint(args...))
+}
+
+// Errorln implements grpclog.LoggerV2.
+func (g *Logger) Errorln(args ...interface{}) {
+	g.log(grpclogDepth, zapcore.ErrorLevel, sprintln(args))
+}
+
+// Errorf implements grpclog.LoggerV2.
+func (g *Logger) Errorf(format string, args ...interface{}) {
+	g.log(grpclogDepth, zapcore.ErrorLevel, fmt.Sprintf(format, args...))
+}
+
+// ErrorDepth implements grpclog.DepthLoggerV2.
+func (g *Logger) ErrorDepth(depth int, args ...interface{}) {
+	g.log(depth+grpclogDepth, zapcore.ErrorLevel, sprintln(args))
+}
+
+// Fatal implements grpclog.LoggerV2.
+func (g *Logger) Fatal(args ...interface{}) {
+	g.log(grpclogDepth, zapcore.FatalLevel, fmt.Sprint(args...))
+}
+
+// Fatalln implements grpclog.LoggerV2.
+func (g *Logger) Fatalln(args ...interface{}) {
+	g.log(grpclogDepth, zapcore.FatalLevel, sprintln(args))
+}
+
+// Fatalf implements grpclog.LoggerV2.
+func (g *Logger) Fatalf(format string, args ...interface{}) {
+	g.log(grpclogDepth, zapcore.FatalLevel, fmt.Sprintf(format, args...))
+}
+
+// FatalDepth implements grpclog.DepthLoggerV2.
+func (g *Logger) FatalDepth(depth int, args ...interface{}) {
+	g.log(depth+grpclogDepth, zapcore.FatalLevel, sprintln(args))
+}
+
+// V implements grpclog.LoggerV2, level 0 follows the info messages and
+// higher levels require debug.
+func (g *Logger) V(level int) bool {
+	if level <= 0 {
+		return g.logger.Core().Enabled(g.infoLevel)
+	}
+	return g.logger.Core().Enabled(zapcore.DebugLevel)
+}
+
+// sprintln 与 fmt.Sprintln 一致，但去掉末尾的换行
+func sprintln(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
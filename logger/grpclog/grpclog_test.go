@@ -0,0 +1,82 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package grpclog
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/grpclog"
+
+	"github.com/TencentBlueKing/bkmonitor-kits/logger"
+	"github.com/TencentBlueKing/bkmonitor-kits/logger/loggertest"
+)
+
+func TestLogger(t *testing.T) {
+	l, logs := loggertest.New()
+	l.SetLevel(logger.InfoLevel)
+	SetLogger(l, Options{})
+
+	_, _, line, _ := runtime.Caller(0)
+	grpclog.Infof("dialing %s", "127.0.0.1:9090")
+	grpclog.Component("core").Warningln("transport", "closing")
+	grpclog.Errorln("handshake failed:", "EOF")
+	grpclog.Component("core").Info("verbose")
+
+	logs.AssertMessages(t, "dialing 127.0.0.1:9090", "[core] transport closing", "handshake failed: EOF", "[core] verbose")
+	logs.AssertLogged(t, logger.InfoLevel, "dialing 127.0.0.1:9090")
+	logs.AssertLogged(t, logger.WarnLevel, "[core] transport closing")
+	logs.AssertLogged(t, logger.ErrorLevel, "handshake failed: EOF")
+	logs.FilterLoggerName(LoggerName).AssertLen(t, 4)
+
+	for i, e := range logs.All() {
+		assert.Equal(t, "grpclog_test.go", filepath.Base(e.Caller.File))
+		assert.Equal(t, line+1+i, e.Caller.Line)
+	}
+
+	assert.True(t, grpclog.V(0))
+	assert.False(t, grpclog.V(2))
+	l.SetLevel(logger.DebugLevel)
+	assert.True(t, grpclog.V(2))
+}
+
+func TestLoggerDowngradeInfo(t *testing.T) {
+	l, logs := loggertest.New()
+	l.SetLevel(logger.InfoLevel)
+	g := NewLogger(l, Options{DowngradeInfo: true})
+
+	g.Info("connectivity changed")
+	g.Warning("retrying")
+	logs.AssertMessages(t, "retrying")
+	assert.False(t, g.V(0))
+
+	l.SetLevel(logger.DebugLevel)
+	g.Infof("state %s", "READY")
+	logs.AssertLogged(t, logger.DebugLevel, "state READY")
+	assert.True(t, g.V(0))
+}
+
+func TestLoggerDisabledAllocs(t *testing.T) {
+	l, _ := loggertest.New()
+	l.SetLevel(logger.InfoLevel)
+	g := NewLogger(l, Options{})
+
+	// 未开启的级别不构造 logger，开启的级别复用按深度缓存的 logger
+	assert.Zero(t, testing.AllocsPerRun(100, func() { g.log(grpclogDepth, zapcore.DebugLevel, "verbose") }))
+	g.log(grpclogDepth+1, zapcore.InfoLevel, "cached")
+	first, _ := g.depths.Load(grpclogDepth + 1)
+	g.log(grpclogDepth+1, zapcore.InfoLevel, "cached")
+	second, _ := g.depths.Load(grpclogDepth + 1)
+	assert.Same(t, first, second)
+}
@@ -0,0 +1,188 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// Hook is called with every entry logged at or above Level, e.g. to count
+// errors in a metric or to raise an alert on DPanic. Fire runs synchronously
+// after the entry is written to the sinks, so it should be fast. It receives
+// the fields added by With followed by those of the call, redacted the same
+// way as the sinks, and is called even for entries dropped by sampling. Fire
+// may log through the same logger, also during Reload. A panic in Fire is
+// recovered and reported to stderr.
+type Hook struct {
+	Level Level
+	Fire  func(ent zapcore.Entry, fields []zapcore.Field)
+}
+
+// AddHook registers hook on the logger and every logger derived from the
+// same New call, it is kept across Reload. Call remove to unregister it.
+func (l Logger) AddHook(hook Hook) (remove func()) {
+	return l.root.load().state.hooks.add(hook)
+}
+
+// AddHook registers hook on the standard logger. See Logger.AddHook.
+func AddHook(hook Hook) (remove func()) {
+	return std.AddHook(hook)
+}
+
+// hookRegistry 保存运行时注册的 hook，写时复制，读取时无锁
+type hookRegistry struct {
+	mut   sync.Mutex
+	hooks atomic.Value // []*Hook
+}
+
+func (r *hookRegistry) load() []*Hook {
+	hooks, _ := r.hooks.Load().([]*Hook)
+	return hooks
+}
+
+func (r *hookRegistry) add(hook Hook) (remove func()) {
+	h := &hook
+	r.mut.Lock()
+	old := r.load()
+	hooks := make([]*Hook, 0, len(old)+1)
+	r.hooks.Store(append(append(hooks, old...), h))
+	r.mut.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mut.Lock()
+			defer r.mut.Unlock()
+			old := r.load()
+			hooks := make([]*Hook, 0, len(old))
+			for _, other := range old {
+				if other != h {
+					hooks = append(hooks, other)
+				}
+			}
+			r.hooks.Store(hooks)
+		})
+	}
+}
+
+// hookCore 对通过 logger 级别的日志调用 hook，与采样无关，被采样丢弃的日志同样会触发 hook。
+// 它不在 generation 的 core 中，由 swapCore 追加在输出之后，hook 执行时不持有 generation 的锁，
+// hook 中再次输出日志不会与热加载互相等待。配置了脱敏时 hook 收到的消息和字段与 sink 一样经过脱敏
+type hookCore struct {
+	levels   *levelCore
+	static   []Hook
+	registry *hookRegistry
+	r        *redactor
+	context  []zapcore.Field // 已经脱敏
+}
+
+func newHookCore(levels *levelCore, static []Hook, registry *hookRegistry, r *redactor) *hookCore {
+	return &hookCore{levels: levels, static: static, registry: registry, r: r}
+}
+
+func (c *hookCore) With(fields []zapcore.Field) *hookCore {
+	context := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	context = append(context, c.context...)
+	context = append(context, c.redact(fields)...)
+	return &hookCore{
+		levels:   c.levels,
+		static:   c.static,
+		registry: c.registry,
+		r:        c.r,
+		context:  context,
+	}
+}
+
+func (c *hookCore) redact(fields []zapcore.Field) []zapcore.Field {
+	if c.r == nil {
+		return fields
+	}
+	return c.r.fields(fields)
+}
+
+// Check 在 ce 中追加调用 hook 的 core，需要在输出的 core 之后调用
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.hooked(ent.Level) && c.levels.enabledFor(ent) {
+		ce = ce.AddCore(ent, hookWriter{c})
+	}
+	return ce
+}
+
+func (c *hookCore) hooked(lvl zapcore.Level) bool {
+	for i := range c.static {
+		if lvl >= zapcore.Level(c.static[i].Level) {
+			return true
+		}
+	}
+	for _, h := range c.registry.load() {
+		if lvl >= zapcore.Level(h.Level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *hookCore) fire(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.r != nil {
+		ent.Message = c.r.redactString(ent.Message)
+		fields = c.r.fields(fields)
+	}
+	if len(c.context) > 0 {
+		all := make([]zapcore.Field, 0, len(c.context)+len(fields))
+		all = append(all, c.context...)
+		fields = append(all, fields...)
+	}
+
+	var errs error
+	for i := range c.static {
+		errs = multierr.Append(errs, runHook(&c.static[i], ent, fields))
+	}
+	for _, h := range c.registry.load() {
+		errs = multierr.Append(errs, runHook(h, ent, fields))
+	}
+	return errs
+}
+
+// runHook 隔离 hook 中的 panic，以错误的形式交给 zap 输出到 ErrorOutput
+func runHook(h *Hook, ent zapcore.Entry, fields []zapcore.Field) (err error) {
+	if ent.Level < zapcore.Level(h.Level) || h.Fire == nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("logger hook panicked: %v", r)
+		}
+	}()
+	h.Fire(ent, fields)
+	return nil
+}
+
+// hookWriter 仅用于在写入时调用 hook
+type hookWriter struct {
+	c *hookCore
+}
+
+func (w hookWriter) Enabled(zapcore.Level) bool        { return true }
+func (w hookWriter) With([]zapcore.Field) zapcore.Core { return w }
+func (w hookWriter) Sync() error                       { return nil }
+
+func (w hookWriter) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, w)
+}
+
+func (w hookWriter) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return w.c.fire(ent, fields)
+}
@@ -0,0 +1,162 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// hookRecorder 记录 hook 收到的日志
+type hookRecorder struct {
+	mut     sync.Mutex
+	entries []zapcore.Entry
+	fields  [][]zapcore.Field
+}
+
+func (r *hookRecorder) fire(ent zapcore.Entry, fields []zapcore.Field) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.entries = append(r.entries, ent)
+	r.fields = append(r.fields, fields)
+}
+
+func (r *hookRecorder) messages() []string {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	msgs := make([]string, 0, len(r.entries))
+	for _, ent := range r.entries {
+		msgs = append(msgs, ent.Message)
+	}
+	return msgs
+}
+
+func TestOptionsHooks(t *testing.T) {
+	rec := new(hookRecorder)
+	l := New(Options{
+		Filename: filepath.Join(t.TempDir(), "app.log"),
+		Level:    "debug",
+		Hooks:    []Hook{{Level: WarnLevel, Fire: rec.fire}},
+	})
+	defer l.Close()
+
+	l.Info("info")
+	l.With("path", "/data").Warnw("warn", "size", 1)
+	l.Error("error")
+
+	assert.Equal(t, []string{"warn", "error"}, rec.messages())
+	ent := rec.entries[0]
+	assert.Equal(t, zapcore.WarnLevel, ent.Level)
+	assert.Equal(t, "hook_test.go", filepath.Base(ent.Caller.File))
+	if assert.Len(t, rec.fields[0], 2) {
+		assert.Equal(t, "path", rec.fields[0][0].Key)
+		assert.Equal(t, "size", rec.fields[0][1].Key)
+	}
+
+	// 低于 logger 级别的日志不会触发 hook
+	l.SetLevel(ErrorLevel)
+	l.Warn("filtered")
+	assert.Equal(t, []string{"warn", "error"}, rec.messages())
+
+	assert.EqualError(t, Options{Hooks: []Hook{{Level: ErrorLevel}}}.Validate(), "hooks[0]: Fire is nil")
+}
+
+func TestAddHook(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := NewWithCore(core)
+
+	rec := new(hookRecorder)
+	remove := l.AddHook(Hook{Level: DebugLevel, Fire: rec.fire})
+	derived := l.Named("host")
+	derived.Debug("first")
+	remove()
+	remove()
+	derived.Debug("second")
+
+	assert.Equal(t, []string{"first"}, rec.messages())
+	assert.Equal(t, 2, logs.Len())
+}
+
+func TestHookPanic(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := NewWithCore(core)
+
+	rec := new(hookRecorder)
+	l.AddHook(Hook{Level: InfoLevel, Fire: func(zapcore.Entry, []zapcore.Field) { panic("boom") }})
+	l.AddHook(Hook{Level: InfoLevel, Fire: rec.fire})
+
+	assert.NotPanics(t, func() { l.Info("hello") })
+	assert.Equal(t, []string{"hello"}, rec.messages())
+	assert.Equal(t, 1, logs.Len())
+
+	err := runHook(&Hook{Fire: func(zapcore.Entry, []zapcore.Field) { panic("boom") }}, zapcore.Entry{}, nil)
+	assert.EqualError(t, err, "logger hook panicked: boom")
+}
+
+func TestHookRedacted(t *testing.T) {
+	rec := new(hookRecorder)
+	l := New(Options{
+		Filename: filepath.Join(t.TempDir(), "app.log"),
+		Hooks:    []Hook{{Level: WarnLevel, Fire: rec.fire}},
+		Redact: &RedactOptions{
+			Keys:          []string{"password"},
+			ValuePatterns: []string{`Bearer\s+\S+`},
+		},
+	})
+	defer l.Close()
+
+	// hook 收到的消息和字段与 sink 一样经过脱敏
+	l.With("password", "in-with").Warnw("login with Bearer abc", "password", "hunter2", "user", "admin")
+	if !assert.Len(t, rec.entries, 1) {
+		return
+	}
+	assert.Equal(t, "login with ******", rec.entries[0].Message)
+
+	// With 添加的字段在前
+	values := make([]interface{}, 0, len(rec.fields[0]))
+	for _, f := range rec.fields[0] {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		values = append(values, enc.Fields[f.Key])
+	}
+	assert.Equal(t, []interface{}{"******", "******", "admin"}, values)
+}
+
+func TestHookReloadAndSampling(t *testing.T) {
+	dir := t.TempDir()
+	static := new(hookRecorder)
+	l := New(Options{
+		Filename: filepath.Join(dir, "app.log"),
+		Hooks:    []Hook{{Level: InfoLevel, Fire: static.fire}},
+	})
+	defer l.Close()
+	runtime := new(hookRecorder)
+	l.AddHook(Hook{Level: InfoLevel, Fire: runtime.fire})
+
+	// 运行时注册的 hook 在热加载后保留，Options 中的 hook 随配置替换
+	assert.NoError(t, l.Reload(Options{
+		Filename: filepath.Join(dir, "app.log"),
+		Sampling: &SamplingOptions{Tick: time.Minute, Initial: 1, Thereafter: 100},
+	}))
+	l.Info("same")
+	l.Info("same")
+
+	assert.Empty(t, static.messages())
+	// 被采样丢弃的日志同样触发 hook
+	assert.Equal(t, []string{"same", "same"}, runtime.messages())
+	assert.Len(t, readLines(t, filepath.Join(dir, "app.log")), 1)
+}
@@ -0,0 +1,186 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLevelBodySize 限制 PUT 请求体大小，避免恶意请求
+const maxLevelBodySize = 1024
+
+// LevelHandler returns an http.Handler which reports and changes the level of
+// the standard logger. It always operates on the current standard logger, so
+// it keeps working after SetOptions.
+//
+// GET responds with the current level. PUT changes it; the level may be sent
+// as a JSON object {"level": "debug", "timeout": "10m"} or as a plain text
+// body, in which case the timeout is read from the "timeout" query parameter.
+// When a timeout is given the level is reverted once it expires. Responses are
+// plain text if the client accepts text/plain, and JSON otherwise.
+func LevelHandler() http.Handler {
+	return &levelHandler{logger: StandardLogger}
+}
+
+// LevelHandler returns an http.Handler which reports and changes the level of
+// l. See the package-level LevelHandler for the protocol.
+func (l Logger) LevelHandler() http.Handler {
+	return &levelHandler{logger: func() Logger { return l }}
+}
+
+type levelPayload struct {
+	Level   string `json:"level"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
+type levelErrorPayload struct {
+	Error string `json:"error"`
+}
+
+type levelHandler struct {
+	logger func() Logger
+
+	mut      sync.Mutex
+	timer    *time.Timer
+	revertTo Level
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	text := wantsText(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeLevel(w, text, levelPayload{Level: h.logger().GetLevel().String()})
+
+	case http.MethodPut:
+		req, err := decodeLevelRequest(r)
+		if err != nil {
+			h.writeError(w, text, http.StatusBadRequest, err)
+			return
+		}
+		level, err := ParseLevel(req.Level)
+		if err != nil {
+			h.writeError(w, text, http.StatusBadRequest, err)
+			return
+		}
+		var timeout time.Duration
+		if req.Timeout != "" {
+			if timeout, err = time.ParseDuration(req.Timeout); err != nil || timeout <= 0 {
+				h.writeError(w, text, http.StatusBadRequest, fmt.Errorf("invalid timeout: %q", req.Timeout))
+				return
+			}
+		}
+
+		h.setLevel(level, timeout)
+		resp := levelPayload{Level: level.String()}
+		if timeout > 0 {
+			resp.Timeout = timeout.String()
+		}
+		h.writeLevel(w, text, resp)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		h.writeError(w, text, http.StatusMethodNotAllowed, errors.New("only GET and PUT are supported"))
+	}
+}
+
+// setLevel 修改日志级别，timeout 大于 0 时到期后恢复到修改前的级别
+func (h *levelHandler) setLevel(level Level, timeout time.Duration) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	l := h.logger()
+	if h.timer != nil {
+		// 已经存在临时修改时，保留最初的级别作为恢复目标
+		h.timer.Stop()
+		h.timer = nil
+	} else {
+		h.revertTo = l.GetLevel()
+	}
+	l.SetLevel(level)
+
+	if timeout <= 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() {
+		h.mut.Lock()
+		defer h.mut.Unlock()
+
+		// 期间被新的请求替换的定时器不再生效
+		if h.timer != timer {
+			return
+		}
+		h.logger().SetLevel(h.revertTo)
+		h.timer = nil
+	})
+	h.timer = timer
+}
+
+func (h *levelHandler) writeLevel(w http.ResponseWriter, text bool, payload levelPayload) {
+	if text {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, payload.Level)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payload)
+}
+
+func (h *levelHandler) writeError(w http.ResponseWriter, text bool, code int, err error) {
+	if text {
+		http.Error(w, err.Error(), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(levelErrorPayload{Error: err.Error()})
+}
+
+func decodeLevelRequest(r *http.Request) (levelPayload, error) {
+	var req levelPayload
+	body := io.LimitReader(r.Body, maxLevelBodySize)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			return req, fmt.Errorf("malformed request body: %v", err)
+		}
+		return req, nil
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return req, err
+	}
+	req.Level = strings.TrimSpace(string(b))
+	if req.Level == "" {
+		req.Level = r.URL.Query().Get("level")
+	}
+	req.Timeout = r.URL.Query().Get("timeout")
+	return req, nil
+}
+
+func wantsText(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept != "" {
+		return strings.Contains(accept, "text/plain")
+	}
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "text/plain")
+}
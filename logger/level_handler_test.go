@@ -0,0 +1,92 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerSetLevel(t *testing.T) {
+	l := New(Options{Stdout: true, Level: "warn"})
+	assert.Equal(t, WarnLevel, l.GetLevel())
+
+	derived := l.With("component", "test")
+	l.SetLevel(DebugLevel)
+	assert.Equal(t, DebugLevel, derived.GetLevel())
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel(" ERROR ")
+	assert.NoError(t, err)
+	assert.Equal(t, ErrorLevel, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestLevelHandler(t *testing.T) {
+	l := New(Options{Stdout: true, Level: "info"})
+	h := l.LevelHandler()
+
+	do := func(method, body, contentType, accept, query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/log/level"+query, strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodGet, "", "", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"info"}`, w.Body.String())
+
+	w = do(http.MethodPut, `{"level":"debug"}`, "application/json", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, DebugLevel, l.GetLevel())
+
+	w = do(http.MethodPut, "error", "text/plain", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "error\n", w.Body.String())
+	assert.Equal(t, ErrorLevel, l.GetLevel())
+
+	w = do(http.MethodPut, `{"level":"verbose"}`, "application/json", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ErrorLevel, l.GetLevel())
+
+	w = do(http.MethodPost, "", "", "text/plain", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestLevelHandlerTimeout(t *testing.T) {
+	l := New(Options{Stdout: true, Level: "info"})
+	h := l.LevelHandler()
+
+	r := httptest.NewRequest(http.MethodPut, "/log/level?timeout=50ms", strings.NewReader("debug"))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, DebugLevel, l.GetLevel())
+
+	assert.Eventually(t, func() bool {
+		return l.GetLevel() == InfoLevel
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,85 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"io"
+	"sync"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// resources 保存 New 创建的输出和后台任务，由所有派生的 logger 共享
+type resources struct {
+	asyncWriters []*asyncWriter
+	closers      []io.Closer
+	reopeners    []reopener
+	netWriters   []*netWriter
+	sampling     *samplingReporter
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// close 依次停止采样汇总、排空异步队列、关闭文件，多次调用只执行一次
+func (r *resources) close() error {
+	r.closeOnce.Do(func() {
+		if r.sampling != nil {
+			r.sampling.stop()
+		}
+		for _, w := range r.asyncWriters {
+			r.closeErr = multierr.Append(r.closeErr, w.Close())
+		}
+		for _, c := range r.closers {
+			r.closeErr = multierr.Append(r.closeErr, c.Close())
+		}
+	})
+	return r.closeErr
+}
+
+// closeCore 仅用于在写入时关闭 resources，由 swapCore 追加在 Fatal 级别日志的输出和 hook 之后，
+// 在进程退出之前关闭所有输出，保证日志不丢失
+type closeCore struct {
+	res *resources
+}
+
+func (c closeCore) Enabled(zapcore.Level) bool                 { return true }
+func (c closeCore) With([]zapcore.Field) zapcore.Core          { return c }
+func (c closeCore) Sync() error                                { return nil }
+func (c closeCore) Write(zapcore.Entry, []zapcore.Field) error { return c.res.close() }
+
+func (c closeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+// Sync flushes any buffered log entries, including the queues of async sinks.
+func (l Logger) Sync() error {
+	return l.sugared.Sync()
+}
+
+// Close flushes any buffered log entries and closes all sinks of the logger.
+// It is shared by all loggers derived from the same New call, which must not
+// be used afterwards unless the logger is reloaded.
+func (l Logger) Close() error {
+	return multierr.Append(l.Sync(), l.root.load().res.close())
+}
+
+// Sync flushes any buffered log entries of the standard logger.
+func Sync() error {
+	return std.Sync()
+}
+
+// Close flushes and closes the standard logger, it should be called before
+// the process exits.
+func Close() error {
+	return std.Close()
+}
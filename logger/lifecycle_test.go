@@ -0,0 +1,63 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoggerClose(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "applog")
+	l := New(Options{
+		Filename: filename,
+		Rotation: RotationDaily,
+		Async:    &AsyncOptions{FlushInterval: time.Hour},
+	})
+
+	l.Info("before close")
+	assert.NoError(t, l.Close())
+	assert.Contains(t, readFile(t, filename), `msg="before close"`)
+
+	// 重复关闭不会报错
+	assert.NoError(t, l.Close())
+	assert.NoError(t, l.Sync())
+}
+
+func TestLoggerFatalFlushes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "applog")
+	l := New(Options{Filename: filename, Async: &AsyncOptions{FlushInterval: time.Hour}})
+
+	// 使用 panic 代替 os.Exit 以便在测试中验证
+	l.sugared = l.sugared.Desugar().WithOptions(zap.OnFatal(zapcore.WriteThenPanic)).Sugar()
+	l.Info("before fatal")
+	assert.Panics(t, func() { l.Fatal("fatal message") })
+
+	content := readFile(t, filename)
+	assert.Contains(t, content, `msg="before fatal"`)
+	assert.Contains(t, content, `msg="fatal message"`)
+}
+
+func TestSetOptionsClosesPrevious(t *testing.T) {
+	dir := t.TempDir()
+	defer SetOptions(Options{Stdout: true, Format: "logfmt"})
+
+	SetOptions(Options{Filename: filepath.Join(dir, "first"), Async: &AsyncOptions{FlushInterval: time.Hour}})
+	Info("first message")
+	SetOptions(Options{Filename: filepath.Join(dir, "second")})
+
+	assert.Contains(t, readFile(t, filepath.Join(dir, "first")), `msg="first message"`)
+}
@@ -12,8 +12,14 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,22 +46,47 @@ func getEncoder() *logfmtEncoder {
 func putEncoder(enc *logfmtEncoder) {
 	enc.EncoderConfig = nil
 	enc.buf = nil
+	enc.prefix = ""
+	enc.configEncoders = false
 	_logfmtPool.Put(enc)
 }
 
 type logfmtEncoder struct {
 	*zapcore.EncoderConfig
-	Encoder    *logfmt.Encoder
-	buf        *buffer.Buffer
-	namespaces []string
+	Encoder *logfmt.Encoder
+	buf     *buffer.Buffer
+
+	// prefix 由 namespace 和嵌套对象的 key 组成，形如 "req.header."，会追加在后续字段的 key 之前
+	prefix string
+
+	// configEncoders 为 true 时 level 和 duration 字段使用 EncodeLevel、EncodeDuration 编码，时间与其他字段一样转义
+	configEncoders bool
+}
+
+// LogfmtOption configures the encoder created by NewLogfmtEncoder.
+type LogfmtOption func(*logfmtEncoder)
+
+// LogfmtConfigEncoders makes the encoder write the level and duration fields
+// with EncodeLevel and EncodeDuration of the EncoderConfig like the json
+// encoder does, and escape times like other values, so a layout containing
+// spaces is quoted. By default the level is always lowercase (level=info),
+// durations use time.Duration.String (backoff=1s) and times are written as
+// EncodeTime returns them.
+func LogfmtConfigEncoders() LogfmtOption {
+	return func(enc *logfmtEncoder) {
+		enc.configEncoders = true
+	}
 }
 
-func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig, opts ...LogfmtOption) zapcore.Encoder {
 	enc := &logfmtEncoder{
 		EncoderConfig: &cfg,
 		buf:           bufferpool.Get(),
 	}
 	enc.Encoder = logfmt.NewEncoder(enc.buf)
+	for _, opt := range opts {
+		opt(enc)
+	}
 
 	return enc
 }
@@ -63,55 +94,119 @@ func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
 func (enc *logfmtEncoder) Reset() {
 	enc.Encoder.Reset()
 	enc.buf.Reset()
-	enc.namespaces = nil
+	enc.prefix = ""
+}
+
+// encodeKeyval 自行处理分隔符，保证克隆后已带有上下文字段的 buffer 能够继续正确追加
+func (enc *logfmtEncoder) encodeKeyval(k string, v interface{}) error {
+	enc.Encoder.Reset()
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+	return enc.Encoder.EncodeKeyval(enc.prefix+k, v)
 }
 
 // implement ObjectEncoder interface https://github.com/uber-go/zap/blob/master/zapcore/encoder.go#L341
 func (enc *logfmtEncoder) AddArray(k string, marshaler zapcore.ArrayMarshaler) error {
-	return enc.Encoder.EncodeKeyval(k, marshaler)
+	arr := &logfmtArrayEncoder{enc: enc}
+	err := marshaler.MarshalLogArray(arr)
+	if encErr := enc.encodeKeyval(k, arr.String()); err == nil {
+		err = encErr
+	}
+	return err
 }
+
+// AddObject 将嵌套对象展开为以 "." 连接的 key，例如 req.header.host=...
 func (enc *logfmtEncoder) AddObject(k string, marshaler zapcore.ObjectMarshaler) error {
-	return enc.Encoder.EncodeKeyval(k, marshaler)
+	prefix := enc.prefix
+	enc.prefix = prefix + k + "."
+	err := marshaler.MarshalLogObject(enc)
+	enc.prefix = prefix
+	return err
 }
 
+// AddReflected 配置了 NewReflectedEncoder 时使用其序列化结果作为值，否则 logfmt 不支持的
+// map、slice 和 struct 等类型使用 json 序列化
 func (enc *logfmtEncoder) AddReflected(k string, value interface{}) error {
-	return enc.Encoder.EncodeKeyval(k, value)
+	if enc.NewReflectedEncoder == nil {
+		if err := logfmt.NewEncoder(ioutil.Discard).EncodeKeyval(k, value); err != logfmt.ErrUnsupportedValueType {
+			return enc.encodeKeyval(k, value)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := enc.newReflectedEncoder(&b).Encode(value); err != nil {
+		return err
+	}
+	return enc.encodeKeyval(k, strings.TrimSuffix(b.String(), "\n"))
 }
 
+// newReflectedEncoder 未配置时与 zap 的 json encoder 一致，使用不转义 HTML 的 encoding/json
+func (enc *logfmtEncoder) newReflectedEncoder(w io.Writer) zapcore.ReflectedEncoder {
+	if enc.NewReflectedEncoder != nil {
+		return enc.NewReflectedEncoder(w)
+	}
+	je := json.NewEncoder(w)
+	je.SetEscapeHTML(false)
+	return je
+}
+
+// AddTime 默认原样输出 EncodeTime 的结果，保持已有采集端依赖的格式；configEncoders 为 true 时与其他字段一样转义
 func (enc *logfmtEncoder) AddTime(k string, v time.Time) {
+	if enc.EncodeTime == nil {
+		enc.AddInt64(k, v.UnixNano())
+		return
+	}
+	if enc.configEncoders {
+		enc.encodeKeyval(k, enc.primitive(func(arr zapcore.PrimitiveArrayEncoder) { enc.EncodeTime(v, arr) }))
+		return
+	}
 	if enc.buf.Len() > 0 {
 		enc.buf.AppendByte(' ')
 	}
-
-	enc.buf.AppendString(fmt.Sprintf("%s=", k))
+	enc.buf.AppendString(fmt.Sprintf("%s%s=", enc.prefix, k))
 	enc.EncodeTime(v, enc)
-	enc.buf.AppendByte(' ')
 }
 
+func (enc *logfmtEncoder) AddDuration(k string, v time.Duration) {
+	if !enc.configEncoders || enc.EncodeDuration == nil {
+		enc.encodeKeyval(k, v)
+		return
+	}
+	enc.encodeKeyval(k, enc.primitive(func(arr zapcore.PrimitiveArrayEncoder) { enc.EncodeDuration(v, arr) }))
+}
+
+// primitive 收集 EncodeLevel、EncodeCaller 等编码函数输出的原始值，再由 logfmt 统一转义
+func (enc *logfmtEncoder) primitive(f func(zapcore.PrimitiveArrayEncoder)) string {
+	arr := &logfmtArrayEncoder{enc: enc, raw: true}
+	f(arr)
+	return strings.Join(arr.elems, ",")
+}
+
+// OpenNamespace 之后添加的字段都会以 key 作为前缀，直到当前对象结束
 func (enc *logfmtEncoder) OpenNamespace(key string) {
-	enc.namespaces = append(enc.namespaces, key)
-}
-
-func (enc *logfmtEncoder) AddBinary(k string, v []byte)          { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddByteString(k string, v []byte)      { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddBool(k string, v bool)              { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddComplex128(k string, v complex128)  { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddComplex64(k string, v complex64)    { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddDuration(k string, v time.Duration) { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddFloat64(k string, v float64)        { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddFloat32(k string, v float32)        { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddInt(k string, v int)                { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddInt64(k string, v int64)            { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddInt32(k string, v int32)            { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddInt16(k string, v int16)            { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddInt8(k string, v int8)              { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddString(k, v string)                 { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddUint(k string, v uint)              { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddUint64(k string, v uint64)          { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddUint32(k string, v uint32)          { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddUint16(k string, v uint16)          { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddUint8(k string, v uint8)            { enc.Encoder.EncodeKeyval(k, v) }
-func (enc *logfmtEncoder) AddUintptr(k string, v uintptr)        { enc.Encoder.EncodeKeyval(k, v) }
+	enc.prefix += key + "."
+}
+
+func (enc *logfmtEncoder) AddBinary(k string, v []byte)         { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddByteString(k string, v []byte)     { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddBool(k string, v bool)             { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddComplex128(k string, v complex128) { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddComplex64(k string, v complex64)   { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddFloat64(k string, v float64)       { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddFloat32(k string, v float32)       { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddInt(k string, v int)               { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddInt64(k string, v int64)           { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddInt32(k string, v int32)           { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddInt16(k string, v int16)           { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddInt8(k string, v int8)             { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddString(k, v string)                { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddUint(k string, v uint)             { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddUint64(k string, v uint64)         { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddUint32(k string, v uint32)         { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddUint16(k string, v uint16)         { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddUint8(k string, v uint8)           { enc.encodeKeyval(k, v) }
+func (enc *logfmtEncoder) AddUintptr(k string, v uintptr)       { enc.encodeKeyval(k, v) }
 
 // implement PrimitiveArrayEncoder interface https://github.com/uber-go/zap/blob/master/zapcore/encoder.go#L402
 func (enc *logfmtEncoder) AppendBool(val bool) {
@@ -185,40 +280,96 @@ func (enc *logfmtEncoder) clone() *logfmtEncoder {
 	clone.EncoderConfig = enc.EncoderConfig
 	clone.buf = bufferpool.Get()
 	clone.Encoder = logfmt.NewEncoder(clone.buf)
-	clone.namespaces = enc.namespaces
+	clone.prefix = enc.prefix
+	clone.configEncoders = enc.configEncoders
 	return clone
 }
 
 func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
 	final := enc.clone()
+	// 固定字段不受 namespace 影响
+	final.prefix = ""
 
 	if final.TimeKey != "" && final.EncodeTime != nil {
 		final.AddTime(final.TimeKey, ent.Time)
 	}
 
 	if final.LevelKey != "" {
-		if err := final.Encoder.EncodeKeyval(final.LevelKey, ent.Level); err != nil {
+		level := ent.Level.String()
+		if final.configEncoders && final.EncodeLevel != nil {
+			if v := final.primitive(func(arr zapcore.PrimitiveArrayEncoder) { final.EncodeLevel(ent.Level, arr) }); v != "" {
+				level = v
+			}
+		}
+		if err := final.encodeKeyval(final.LevelKey, level); err != nil {
 			return nil, err
 		}
 	}
 
-	if ent.Caller.Defined {
-		if err := final.Encoder.EncodeKeyval(final.CallerKey, ent.Caller.TrimmedPath()); err != nil {
+	if ent.LoggerName != "" && final.NameKey != "" {
+		nameEncoder := final.EncodeName
+		if nameEncoder == nil {
+			nameEncoder = zapcore.FullNameEncoder
+		}
+		name := final.primitive(func(arr zapcore.PrimitiveArrayEncoder) { nameEncoder(ent.LoggerName, arr) })
+		if name == "" {
+			name = ent.LoggerName
+		}
+		if err := final.encodeKeyval(final.NameKey, name); err != nil {
 			return nil, err
 		}
 	}
 
+	if ent.Caller.Defined {
+		if final.CallerKey != "" {
+			caller := ent.Caller.TrimmedPath()
+			if final.EncodeCaller != nil {
+				if v := final.primitive(func(arr zapcore.PrimitiveArrayEncoder) { final.EncodeCaller(ent.Caller, arr) }); v != "" {
+					caller = v
+				}
+			}
+			if err := final.encodeKeyval(final.CallerKey, caller); err != nil {
+				return nil, err
+			}
+		}
+		if final.FunctionKey != "" {
+			if err := final.encodeKeyval(final.FunctionKey, ent.Caller.Function); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if final.MessageKey != "" {
-		if err := final.Encoder.EncodeKeyval(final.MessageKey, ent.Message); err != nil {
+		if err := final.encodeKeyval(final.MessageKey, ent.Message); err != nil {
 			return nil, err
 		}
 	}
 
+	// 追加 With 添加的上下文字段
+	if enc.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		final.buf.Write(enc.buf.Bytes())
+	}
+
+	final.prefix = enc.prefix
 	addFields(final, fields)
 
-	// add endline
-	if err := final.Encoder.EndRecord(); err != nil {
-		return nil, err
+	// 堆栈作为普通字段输出，换行等字符由 logfmt 转义
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.prefix = ""
+		if err := final.encodeKeyval(final.StacktraceKey, ent.Stack); err != nil {
+			return nil, err
+		}
+	}
+
+	if !final.SkipLineEnding {
+		if final.LineEnding != "" {
+			final.buf.AppendString(final.LineEnding)
+		} else {
+			final.buf.AppendString(zapcore.DefaultLineEnding)
+		}
 	}
 
 	ret := final.buf
@@ -226,6 +377,100 @@ func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field)
 	return ret, nil
 }
 
+// logfmtArrayEncoder 将数组渲染为 [a,b,c] 形式的单个值，嵌套对象渲染为 {k=v k2=v2}
+type logfmtArrayEncoder struct {
+	enc   *logfmtEncoder
+	elems []string
+
+	// raw 为 true 时字符串不加引号，用于收集单个原始值
+	raw bool
+}
+
+func (arr *logfmtArrayEncoder) String() string {
+	return "[" + strings.Join(arr.elems, ",") + "]"
+}
+
+func (arr *logfmtArrayEncoder) append(s string) {
+	arr.elems = append(arr.elems, s)
+}
+
+func (arr *logfmtArrayEncoder) AppendArray(v zapcore.ArrayMarshaler) error {
+	nested := &logfmtArrayEncoder{enc: arr.enc}
+	err := v.MarshalLogArray(nested)
+	arr.append(nested.String())
+	return err
+}
+
+func (arr *logfmtArrayEncoder) AppendObject(v zapcore.ObjectMarshaler) error {
+	nested := arr.enc.clone()
+	nested.prefix = ""
+	err := v.MarshalLogObject(nested)
+	arr.append("{" + nested.buf.String() + "}")
+	nested.buf.Free()
+	putEncoder(nested)
+	return err
+}
+
+func (arr *logfmtArrayEncoder) AppendReflected(v interface{}) error {
+	arr.appendString(fmt.Sprint(v))
+	return nil
+}
+
+func (arr *logfmtArrayEncoder) AppendDuration(v time.Duration) {
+	if arr.enc.EncodeDuration == nil {
+		arr.append(v.String())
+		return
+	}
+	arr.enc.EncodeDuration(v, arr)
+}
+
+func (arr *logfmtArrayEncoder) AppendTime(v time.Time) {
+	if arr.enc.EncodeTime == nil {
+		arr.AppendInt64(v.UnixNano())
+		return
+	}
+	arr.enc.EncodeTime(v, arr)
+}
+
+// appendString 元素中包含分隔符等特殊字符时加引号，保证输出无歧义
+func (arr *logfmtArrayEncoder) appendString(v string) {
+	if arr.raw {
+		arr.append(v)
+		return
+	}
+	if v == "" || strings.ContainsAny(v, " ,[]{}\"=") {
+		v = strconv.Quote(v)
+	}
+	arr.append(v)
+}
+
+func (arr *logfmtArrayEncoder) AppendBool(v bool)         { arr.append(strconv.FormatBool(v)) }
+func (arr *logfmtArrayEncoder) AppendByteString(v []byte) { arr.appendString(string(v)) }
+func (arr *logfmtArrayEncoder) AppendComplex128(v complex128) {
+	arr.append(strconv.FormatComplex(v, 'f', -1, 128))
+}
+func (arr *logfmtArrayEncoder) AppendComplex64(v complex64) {
+	arr.append(strconv.FormatComplex(complex128(v), 'f', -1, 64))
+}
+func (arr *logfmtArrayEncoder) AppendFloat64(v float64) {
+	arr.append(strconv.FormatFloat(v, 'f', -1, 64))
+}
+func (arr *logfmtArrayEncoder) AppendFloat32(v float32) {
+	arr.append(strconv.FormatFloat(float64(v), 'f', -1, 32))
+}
+func (arr *logfmtArrayEncoder) AppendInt(v int)         { arr.AppendInt64(int64(v)) }
+func (arr *logfmtArrayEncoder) AppendInt64(v int64)     { arr.append(strconv.FormatInt(v, 10)) }
+func (arr *logfmtArrayEncoder) AppendInt32(v int32)     { arr.AppendInt64(int64(v)) }
+func (arr *logfmtArrayEncoder) AppendInt16(v int16)     { arr.AppendInt64(int64(v)) }
+func (arr *logfmtArrayEncoder) AppendInt8(v int8)       { arr.AppendInt64(int64(v)) }
+func (arr *logfmtArrayEncoder) AppendString(v string)   { arr.appendString(v) }
+func (arr *logfmtArrayEncoder) AppendUint(v uint)       { arr.AppendUint64(uint64(v)) }
+func (arr *logfmtArrayEncoder) AppendUint64(v uint64)   { arr.append(strconv.FormatUint(v, 10)) }
+func (arr *logfmtArrayEncoder) AppendUint32(v uint32)   { arr.AppendUint64(uint64(v)) }
+func (arr *logfmtArrayEncoder) AppendUint16(v uint16)   { arr.AppendUint64(uint64(v)) }
+func (arr *logfmtArrayEncoder) AppendUint8(v uint8)     { arr.AppendUint64(uint64(v)) }
+func (arr *logfmtArrayEncoder) AppendUintptr(v uintptr) { arr.AppendUint64(uint64(v)) }
+
 func addFields(enc zapcore.ObjectEncoder, fields []zapcore.Field) {
 	for i := range fields {
 		fields[i].AddTo(enc)
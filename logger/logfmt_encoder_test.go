@@ -63,14 +63,14 @@ func TestLoggerWithLogfmtEncoder(t *testing.T) {
 	buf := bufferpool.Get()
 
 	core := zapcore.NewCore(encoder, zapcore.AddSync(buf), zapcore.Level(0))
-	sugar := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1)).Sugar()
+	sugar := zap.New(core, zap.AddCaller()).Sugar()
 
 	sugar.Infof("Failed to fetch URL: %s", "url")
 
 	// 去掉动态的 ts 字段
 	removedTs := buf.String()[27:]
 
-	assert.Equal(t, "level=info caller=testing/testing.go:1123 msg=\"Failed to fetch URL: url\"\n", removedTs, "Unexpected encoder output")
+	assert.Equal(t, "level=info caller=logger/logfmt_encoder_test.go:68 msg=\"Failed to fetch URL: url\"\n", removedTs, "Unexpected encoder output")
 
 	buf.Reset()
 	valLogger := sugar.With("component", "thanos")
@@ -84,5 +84,149 @@ func TestLoggerWithLogfmtEncoder(t *testing.T) {
 	)
 	removedTs = buf.String()[27:]
 
-	assert.Equal(t, "level=warn caller=testing/testing.go:1123 msg=\"failed to fetch URL\" url=url attempt=3 backoff=1s component=logger\n", removedTs, "Unexpected encoder output")
+	assert.Equal(t, "level=warn caller=logger/logfmt_encoder_test.go:78 msg=\"failed to fetch URL\" component=thanos url=url attempt=3 backoff=1s component=logger\n", removedTs, "Unexpected encoder output")
+}
+
+func TestLogfmtEncoderWithContextFields(t *testing.T) {
+	l, buf := newBufferLogger(DebugLevel)
+
+	l.With("a", "b").With("c", 1).Info("x")
+	l.Info("y")
+	assert.Equal(t, "level=info msg=x a=b c=1\nlevel=info msg=y\n", buf.String())
+}
+
+type testHeader struct {
+	host string
+	tags []string
+}
+
+func (h testHeader) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("host", h.host)
+	return enc.AddArray("tags", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, tag := range h.tags {
+			arr.AppendString(tag)
+		}
+		return nil
+	}))
+}
+
+func TestEncoderNestedFields(t *testing.T) {
+	request := zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("method", "GET")
+		return enc.AddObject("header", testHeader{host: "bk.com", tags: []string{"a", "b c"}})
+	})
+
+	tests := []struct {
+		desc     string
+		expected string
+		f        func(zapcore.Encoder)
+	}{
+		{"object", `req.method=GET req.header.host=bk.com req.header.tags="[a,\"b c\"]"`, func(e zapcore.Encoder) {
+			e.AddObject("req", request)
+		}},
+		{"namespace", `ns.k=v ns.inner.k=1`, func(e zapcore.Encoder) {
+			e.OpenNamespace("ns")
+			e.AddString("k", "v")
+			e.OpenNamespace("inner")
+			e.AddInt("k", 1)
+		}},
+		{"object closes namespace", `obj.ns.a=1 b=2`, func(e zapcore.Encoder) {
+			e.AddObject("obj", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.OpenNamespace("ns")
+				enc.AddInt("a", 1)
+				return nil
+			}))
+			e.AddInt("b", 2)
+		}},
+		{"array", `k=[1,2,3]`, func(e zapcore.Encoder) {
+			e.AddArray("k", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+				for i := 1; i <= 3; i++ {
+					arr.AppendInt(i)
+				}
+				return nil
+			}))
+		}},
+		{"array of objects", `k="[{host=a tags=[]},[true,false]]"`, func(e zapcore.Encoder) {
+			e.AddArray("k", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+				arr.AppendObject(testHeader{host: "a"})
+				return arr.AppendArray(zapcore.ArrayMarshalerFunc(func(inner zapcore.ArrayEncoder) error {
+					inner.AppendBool(true)
+					inner.AppendBool(false)
+					return nil
+				}))
+			}))
+		}},
+		{"reflected map", `k="{\"a\":1,\"b\":\"<x>\"}"`, func(e zapcore.Encoder) {
+			e.AddReflected("k", map[string]interface{}{"a": 1, "b": "<x>"})
+		}},
+		{"reflected scalar", `k=7`, func(e zapcore.Encoder) {
+			type port int
+			e.AddReflected("k", port(7))
+		}},
+	}
+
+	for _, tt := range tests {
+		assertOutput(t, tt.desc, tt.expected, tt.f)
+	}
+}
+
+func TestLoggerWithNamespace(t *testing.T) {
+	l, buf := newBufferLogger(DebugLevel)
+
+	l.With(zap.Namespace("ctx"), "a", 1).Infow("hello", "b", 2, zap.Strings("tags", []string{"x", "y"}))
+	assert.Equal(t, "level=info msg=hello ctx.a=1 ctx.b=2 ctx.tags=[x,y]\n", buf.String())
+}
+
+func TestEncoderTimeEscaped(t *testing.T) {
+	cfg := zapcore.EncoderConfig{
+		MessageKey: "msg",
+		TimeKey:    "ts",
+		EncodeTime: zapcore.TimeEncoderOfLayout(`2006-01-02 15:04:05 "MST"`),
+	}
+	ts := time.Date(2026, 10, 18, 13, 30, 0, 0, time.UTC)
+
+	// 默认原样输出，保持已有格式
+	buf, err := NewLogfmtEncoder(cfg).EncodeEntry(zapcore.Entry{Time: ts, Message: "done"}, []zapcore.Field{zap.Time("start", ts)})
+	assert.NoError(t, err)
+	assert.Equal(t, `ts=2026-10-18 13:30:00 "UTC" msg=done start=2026-10-18 13:30:00 "UTC"`+"\n", buf.String())
+
+	buf, err = NewLogfmtEncoder(cfg, LogfmtConfigEncoders()).EncodeEntry(zapcore.Entry{Time: ts, Message: "done"}, []zapcore.Field{zap.Time("start", ts)})
+	assert.NoError(t, err)
+	assert.Equal(t, `ts="2026-10-18 13:30:00 \"UTC\"" msg=done start="2026-10-18 13:30:00 \"UTC\""`+"\n", buf.String())
+}
+
+func TestEncoderEntryConfig(t *testing.T) {
+	ent := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		LoggerName: "host.watcher",
+		Message:    "failed",
+		Caller:     zapcore.NewEntryCaller(0, "/src/bkmonitor-kits/host/watcher.go", 42, true),
+		Stack:      "goroutine 1 [running]:\n\tmain.main()",
+	}
+	ent.Caller.Function = "host.(*idWatcher).updateInfo"
+
+	cfg := zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    "func",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     "\r\n",
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeCaller:   zapcore.FullCallerEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+	}
+
+	buf, err := NewLogfmtEncoder(cfg, LogfmtConfigEncoders()).EncodeEntry(ent, []zapcore.Field{zap.Duration("cost", 1500*time.Millisecond)})
+	assert.NoError(t, err)
+	assert.Equal(t, `level=ERROR logger=host.watcher caller=/src/bkmonitor-kits/host/watcher.go:42 `+
+		`func=host.(*idWatcher).updateInfo msg=failed cost=1500 `+
+		`stacktrace="goroutine 1 [running]:\n\tmain.main()"`+"\r\n", buf.String())
+
+	// key 为空的部分被忽略
+	cfg = zapcore.EncoderConfig{MessageKey: "msg", SkipLineEnding: true}
+	buf, err = NewLogfmtEncoder(cfg).EncodeEntry(ent, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `msg=failed`, buf.String())
 }
@@ -11,13 +11,13 @@
 package logger
 
 import (
-	"os"
-	"path/filepath"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 type Level int8
@@ -59,6 +59,20 @@ const (
 	FatalLevel
 )
 
+// String returns a lower-case ASCII representation of the log level.
+func (l Level) String() string {
+	return zapcore.Level(l).String()
+}
+
+// ParseLevel parses a level name such as "debug" or "WARN" into a Level.
+func ParseLevel(text string) (Level, error) {
+	level, ok := loggerLevelMap[strings.ToLower(strings.TrimSpace(text))]
+	if !ok {
+		return InfoLevel, fmt.Errorf("unrecognized level: %q", text)
+	}
+	return level, nil
+}
+
 // Options is the option set for Logger.
 type Options struct {
 	// Stdout sets the writer as stdout if it is true.
@@ -67,6 +81,13 @@ type Options struct {
 	// logger ouput format, Valid values are "json", "console" and "logfmt", default is logfmt
 	Format string `yaml:"format"`
 
+	// LogfmtEncoderConfig makes the logfmt sinks write the level and durations
+	// with the EncodeLevel and EncodeDuration of the encoder config like json
+	// (level=INFO cost=1.5) and quote the timestamp (ts="2006-01-02 15:04:05.000").
+	// The default false keeps the established output (level=info cost=1.5s
+	// ts=2006-01-02 15:04:05.000) that existing collectors parse.
+	LogfmtEncoderConfig bool `yaml:"logfmt_encoder_config"`
+
 	// Filename is the file to write logs to.  Backup log files will be retained
 	// in the same directory.
 	Filename string `yaml:"filename"`
@@ -86,13 +107,90 @@ type Options struct {
 	// deleted.)
 	MaxBackups int `yaml:"max_backups"`
 
+	// Rotation enables time based rotation, Valid values are "hourly" and
+	// "daily". It can be combined with MaxSize. Empty means size based only.
+	Rotation string `yaml:"rotation"`
+
+	// RotationPattern is the time layout appended to the backup filename,
+	// default is "2006-01-02-15" for hourly, "2006-01-02" for daily and
+	// "2006-01-02T15-04-05.000" when files are only rotated by size.
+	RotationPattern string `yaml:"rotation_pattern"`
+
+	// Compress is the algorithm used to compress rotated files in the
+	// background, Valid value is "gzip". Empty means no compression.
+	Compress string `yaml:"compress"`
+
+	// CompressLevel is the compression level, 0 means gzip.DefaultCompression.
+	CompressLevel int `yaml:"compress_level"`
+
+	// DisableRotation leaves the rotation to external tools such as
+	// logrotate, see SinkOptions.DisableRotation.
+	DisableRotation bool `yaml:"disable_rotation"`
+
 	// Level is a logging priority. Higher levels are more important.
 	Level string `yaml:"level"`
+
+	// Sampling limits the number of entries with the same level and message
+	// logged per second. Nil means no sampling.
+	Sampling *SamplingOptions `yaml:"sampling"`
+
+	// Async makes every sink write entries in a background goroutine through
+	// a bounded queue. Nil means writing synchronously.
+	Async *AsyncOptions `yaml:"async"`
+
+	// Levels overrides Level for named loggers, keyed by logger name prefix,
+	// e.g. {"host": "warn", "register.consul": "debug"}. The longest matching
+	// prefix wins, names without a match use Level.
+	Levels map[string]string `yaml:"levels"`
+
+	// Redact hides sensitive values such as tokens and passwords in all
+	// sinks. Nil means no redaction.
+	Redact *RedactOptions `yaml:"redact"`
+
+	// Hooks are called with the entries at or above their level, more can be
+	// added at runtime with AddHook. They can only be set in code.
+	Hooks []Hook `yaml:"-"`
+
+	// Metrics counts the entries per level and logger name and the bytes
+	// written by the sinks, see Stats and MetricsHandler. Nil means no
+	// instrumentation.
+	Metrics *MetricsOptions `yaml:"metrics"`
+
+	// Sinks is the list of outputs entries are written to. When it is empty,
+	// a single sink is built from Stdout, Format and the file options above.
+	Sinks []SinkOptions `yaml:"sinks"`
+}
+
+// sinks returns the configured sinks, falling back to the legacy single output.
+func (opt Options) sinks() []SinkOptions {
+	if len(opt.Sinks) > 0 {
+		return opt.Sinks
+	}
+
+	sink := SinkOptions{
+		Type:       SinkFile,
+		Format:     opt.Format,
+		Filename:   opt.Filename,
+		MaxSize:    opt.MaxSize,
+		MaxAge:     opt.MaxAge,
+		MaxBackups: opt.MaxBackups,
+
+		Rotation:        opt.Rotation,
+		RotationPattern: opt.RotationPattern,
+		Compress:        opt.Compress,
+		CompressLevel:   opt.CompressLevel,
+		DisableRotation: opt.DisableRotation,
+	}
+	if opt.Stdout {
+		sink.Type = SinkStdout
+	}
+	return []SinkOptions{sink}
 }
 
 // Logger represents the global SugaredLogger
 type Logger struct {
 	sugared *zap.SugaredLogger
+	root    *loggerRoot
 }
 
 // With adds a variadic number of fields to the logging context. It accepts a
@@ -100,7 +198,25 @@ type Logger struct {
 // processing pairs, the first element of the pair is used as the field key
 // and the second as the field value.
 func (l Logger) With(args ...interface{}) Logger {
-	return Logger{sugared: l.sugared.With(args...)}
+	l.sugared = l.sugared.With(args...)
+	return l
+}
+
+// Desugar returns the underlying zap logger for adapters which need typed
+// fields or their own caller information. It follows Reload like l does.
+func (l Logger) Desugar() *zap.Logger {
+	return l.sugared.Desugar().WithOptions(zap.AddCallerSkip(-1))
+}
+
+// SetLevel changes the logging level at runtime. The change is shared by all
+// loggers derived from l via With, until the next Reload.
+func (l Logger) SetLevel(level Level) {
+	l.root.load().level.SetLevel(zapcore.Level(level))
+}
+
+// GetLevel returns the current minimum enabled logging level.
+func (l Logger) GetLevel() Level {
+	return Level(l.root.load().level.Level())
 }
 
 // Println is the alias for Info
@@ -216,50 +332,101 @@ func (l Logger) Fatalw(msg string, keysAndValues ...interface{}) {
 	l.sugared.Fatalw(msg, keysAndValues...)
 }
 
-// New returns the logger instance with Production Config by default.
+// New returns the logger instance with Production Config by default. It
+// panics if the options are invalid, use Build to handle the error instead.
 func New(opt Options) Logger {
+	l, err := Build(opt)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Build returns the logger instance with Production Config by default. The
+// options are checked by Validate before any sink is opened.
+func Build(opt Options) (Logger, error) {
+	gen, err := buildGeneration(opt, new(loggerState))
+	if err != nil {
+		return Logger{}, err
+	}
+	return newLogger(gen, zap.AddCaller(), zap.AddCallerSkip(1)), nil
+}
+
+// NewWithCore returns a logger writing to core instead of the sinks built
+// from Options, mainly for tests and for adapting other zap based outputs.
+// The level of the logger starts at debug so that core decides what is
+// written, SetLevel still works on top of it.
+func NewWithCore(core zapcore.Core) Logger {
+	level := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	res := new(resources)
+	state := new(loggerState)
+	levels, _ := newLevelCore(core, level, nil)
+	hooks := newHookCore(levels, nil, &state.hooks, nil)
+	gen := &generation{core: levels, hooks: hooks, level: level, res: res, state: state}
+	return newLogger(gen, zap.AddCaller(), zap.AddCallerSkip(1))
+}
+
+// buildGeneration 根据配置构造所有输出，失败时关闭已经打开的输出；state 在热加载时沿用
+func buildGeneration(opt Options, state *loggerState) (*generation, error) {
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 		enc.AppendString(t.Local().Format("2006-01-02 15:04:05.000"))
 	}
 	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
 
-	var encoder zapcore.Encoder
-	switch opt.Format {
-	case "json":
-		encoder = zapcore.NewJSONEncoder(encoderConfig)
-	case "console":
-		encoder = zapcore.NewConsoleEncoder(encoderConfig)
-	case "logfmt":
-		encoder = NewLogfmtEncoder(encoderConfig)
-	default:
-		encoder = NewLogfmtEncoder(encoderConfig)
-	}
+	// 在这里将level转换为实际的level值, 使用 AtomicLevel 以便运行时调整
+	// 已经过 Validate 校验，为空时 ParseLevel 返回 InfoLevel
+	lvl, _ := ParseLevel(opt.Level)
+	level := zap.NewAtomicLevelAt(zapcore.Level(lvl))
 
-	var w zapcore.WriteSyncer
-	if opt.Stdout {
-		w = zapcore.AddSync(os.Stdout)
-	} else {
-		// 初始化日志目录
-		if err := os.MkdirAll(filepath.Dir(opt.Filename), os.ModePerm); err != nil {
-			panic(err)
+	var red *redactor
+	if opt.Redact != nil {
+		var err error
+		if red, err = newRedactor(*opt.Redact); err != nil {
+			return nil, err
 		}
+	}
 
-		w = zapcore.AddSync(&lumberjack.Logger{
-			Filename:   opt.Filename,
-			MaxSize:    opt.MaxSize,
-			MaxBackups: opt.MaxBackups,
-			MaxAge:     opt.MaxAge,
-			LocalTime:  true,
-		})
+	var m *metrics
+	if opt.Metrics != nil {
+		m = &state.metrics
+		m.namespace.Store(opt.Metrics.Namespace)
+	}
+
+	res := new(resources)
+	sinks := opt.sinks()
+	cores := make([]zapcore.Core, 0, len(sinks))
+	for _, sink := range sinks {
+		core, err := newSinkCore(sink, opt, encoderConfig, res, m)
+		if err != nil {
+			res.close()
+			return nil, err
+		}
+		if red != nil {
+			core = &redactCore{Core: core, r: red}
+		}
+		cores = append(cores, core)
 	}
 
-	// 在这里将level转换为实际的level值
-	level := loggerLevelMap[opt.Level]
+	core := zapcore.NewTee(cores...)
+	if m != nil {
+		core = &metricsCore{Core: core, m: m}
+	}
+	if opt.Sampling != nil {
+		core, res.sampling = newSampledCore(core, *opt.Sampling)
+	}
+	levels, err := newLevelCore(core, level, opt.Levels)
+	if err != nil {
+		res.close()
+		return nil, err
+	}
 
-	core := zapcore.NewCore(encoder, w, zapcore.Level(level))
-	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
-	return Logger{sugared: logger.Sugar()}
+	hooks := newHookCore(levels, opt.Hooks, &state.hooks, red)
+	return &generation{core: levels, hooks: hooks, level: level, res: res, state: state}, nil
 }
 
 var std = New(Options{Stdout: true, Format: "logfmt"})
@@ -269,9 +436,47 @@ func StandardLogger() Logger {
 	return std
 }
 
-// SetOptions sets the options for the standard logger.
+// ReplaceStandardLogger makes the standard logger and every logger derived
+// from it write through the current configuration of l, until restore is
+// called. Fields added to l by With are not carried over. The replaced
+// configuration is neither closed nor reloaded, so it is mainly useful in
+// tests.
+func ReplaceStandardLogger(l Logger) (restore func()) {
+	// 使用新的 generation，标准 logger 在替换期间热加载时不会影响 l 本身
+	gen := l.root.load()
+	root := std.root
+	root.mut.Lock()
+	old := root.load()
+	root.gen.Store(&generation{core: gen.core, hooks: gen.hooks, level: gen.level, res: new(resources), state: gen.state})
+	root.mut.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			root.mut.Lock()
+			root.gen.Store(old)
+			root.mut.Unlock()
+		})
+	}
+}
+
+// SetOptions sets the options for the standard logger and all loggers
+// derived from it. The previous sinks are flushed and closed. It panics if
+// the options are invalid, use Reload to handle the error instead.
 func SetOptions(opt Options) {
-	std = New(opt)
+	if err := std.Reload(opt); err != nil {
+		panic(err)
+	}
+}
+
+// SetLevel changes the logging level of the standard logger at runtime.
+func SetLevel(level Level) {
+	std.SetLevel(level)
+}
+
+// GetLevel returns the current logging level of the standard logger.
+func GetLevel() Level {
+	return std.GetLevel()
 }
 
 // With adds a variadic number of fields to the logging context. It accepts a
@@ -0,0 +1,55 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"bytes"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// syncBuffer 并发安全的 buffer，用于后台 goroutine 也会写入日志的场景
+type syncBuffer struct {
+	mut sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.buf.Reset()
+}
+
+// newBufferLogger 构造输出到内存的 logger，去掉时间和调用者字段便于断言
+func newBufferLogger(level Level) (Logger, *syncBuffer) {
+	buf := new(syncBuffer)
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.TimeKey = ""
+	cfg.CallerKey = ""
+
+	atomicLevel := zap.NewAtomicLevelAt(zapcore.Level(level))
+	core := zapcore.NewCore(NewLogfmtEncoder(cfg), zapcore.AddSync(buf), atomicLevel)
+	return newLogger(&generation{core: core, level: atomicLevel, res: new(resources), state: new(loggerState)}), buf
+}
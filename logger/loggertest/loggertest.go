@@ -0,0 +1,202 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+// Package loggertest records the entries written through logger.Logger in
+// memory so that tests can assert on them.
+//
+//	l, logs := loggertest.New()
+//	doSomething(l)
+//	logs.FilterLevel(logger.WarnLevel).FilterField("path", "/tmp/a").AssertLen(t, 1)
+//
+// Code using the package level functions of logger can be observed with
+// ObserveStandard.
+package loggertest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/TencentBlueKing/bkmonitor-kits/logger"
+)
+
+// Entry is an entry recorded by an observed logger, with the fields added
+// by With and at the call site in Context.
+type Entry = observer.LoggedEntry
+
+// Logs is the entries recorded by an observed logger. The Filter methods
+// return a snapshot and can be chained.
+type Logs struct {
+	logs *observer.ObservedLogs
+}
+
+// New returns a logger recording every entry in memory, the level of the
+// logger starts at debug and can be changed with SetLevel.
+func New() (logger.Logger, *Logs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return logger.NewWithCore(core), &Logs{logs: logs}
+}
+
+// ObserveStandard makes the standard logger, and every logger derived from
+// it, record entries in memory until the test finishes.
+func ObserveStandard(t testing.TB) *Logs {
+	l, logs := New()
+	restore := logger.ReplaceStandardLogger(l)
+	t.Cleanup(restore)
+	return logs
+}
+
+// Len returns the number of entries.
+func (o *Logs) Len() int {
+	return o.logs.Len()
+}
+
+// All returns a copy of all entries.
+func (o *Logs) All() []Entry {
+	return o.logs.All()
+}
+
+// TakeAll returns all entries and removes them from the recorder, it must
+// be called on the Logs returned by New or ObserveStandard.
+func (o *Logs) TakeAll() []Entry {
+	return o.logs.TakeAll()
+}
+
+// Messages returns the messages of all entries in order.
+func (o *Logs) Messages() []string {
+	entries := o.logs.All()
+	msgs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+// FilterLevel returns the entries logged at exactly level.
+func (o *Logs) FilterLevel(level logger.Level) *Logs {
+	return &Logs{logs: o.logs.FilterLevelExact(zapcore.Level(level))}
+}
+
+// FilterMessage returns the entries with message msg.
+func (o *Logs) FilterMessage(msg string) *Logs {
+	return &Logs{logs: o.logs.FilterMessage(msg)}
+}
+
+// FilterMessageSnippet returns the entries whose message contains snippet.
+func (o *Logs) FilterMessageSnippet(snippet string) *Logs {
+	return &Logs{logs: o.logs.FilterMessageSnippet(snippet)}
+}
+
+// FilterLoggerName returns the entries logged by the logger named name.
+func (o *Logs) FilterLoggerName(name string) *Logs {
+	return &Logs{logs: o.logs.Filter(func(e Entry) bool {
+		return e.LoggerName == name
+	})}
+}
+
+// FilterField returns the entries having a field key equal to value. The
+// value is compared the way it is logged, e.g. FilterField("n", 1) matches
+// Infow("msg", "n", 1) and With("n", int64(1)).
+func (o *Logs) FilterField(key string, value interface{}) *Logs {
+	field := zap.Any(key, value)
+	return &Logs{logs: o.logs.Filter(func(e Entry) bool {
+		for _, f := range e.Context {
+			if f.Equals(field) {
+				return true
+			}
+		}
+		return false
+	})}
+}
+
+// FilterFieldKey returns the entries having a field key, whatever its value.
+func (o *Logs) FilterFieldKey(key string) *Logs {
+	return &Logs{logs: o.logs.FilterFieldKey(key)}
+}
+
+// AssertLen checks that there are exactly n entries.
+func (o *Logs) AssertLen(t testing.TB, n int) bool {
+	t.Helper()
+	if got := o.logs.Len(); got != n {
+		t.Errorf("expected %d log entries, got %d:\n%s", n, got, o)
+		return false
+	}
+	return true
+}
+
+// AssertEmpty checks that there is no entry.
+func (o *Logs) AssertEmpty(t testing.TB) bool {
+	t.Helper()
+	return o.AssertLen(t, 0)
+}
+
+// AssertLogged checks that at least one entry was logged at level with
+// message msg and all the given fields, written as key-value pairs.
+func (o *Logs) AssertLogged(t testing.TB, level logger.Level, msg string, keysAndValues ...interface{}) bool {
+	t.Helper()
+	if len(keysAndValues)%2 != 0 {
+		t.Errorf("odd number of arguments passed as key-value pairs: %v", keysAndValues)
+		return false
+	}
+
+	matched := o.FilterLevel(level).FilterMessage(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		matched = matched.FilterField(fmt.Sprint(keysAndValues[i]), keysAndValues[i+1])
+	}
+	if matched.Len() == 0 {
+		t.Errorf("no %s entry %q with fields %v in:\n%s", level, msg, keysAndValues, o)
+		return false
+	}
+	return true
+}
+
+// AssertMessages checks the messages of all entries in order.
+func (o *Logs) AssertMessages(t testing.TB, msgs ...string) bool {
+	t.Helper()
+	got := o.Messages()
+	equal := len(got) == len(msgs)
+	for i := 0; equal && i < len(got); i++ {
+		equal = got[i] == msgs[i]
+	}
+	if !equal {
+		t.Errorf("expected messages %q, got %q", msgs, got)
+	}
+	return equal
+}
+
+// String returns one line per entry, used in the failure messages.
+func (o *Logs) String() string {
+	var b strings.Builder
+	for _, e := range o.logs.All() {
+		fmt.Fprintf(&b, "\t%s\t%s", e.Level.CapitalString(), e.Message)
+		if e.LoggerName != "" {
+			fmt.Fprintf(&b, "\tlogger=%s", e.LoggerName)
+		}
+		fields := e.ContextMap()
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "\t%s=%v", k, fields[k])
+		}
+		b.WriteByte('\n')
+	}
+	if b.Len() == 0 {
+		return "\t(no entries)\n"
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package loggertest
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-kits/logger"
+)
+
+// fakeT 记录断言失败的信息，用于测试断言本身
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestObservedLogger(t *testing.T) {
+	l, logs := New()
+	l.Named("host").With("path", "/data").Warnw("invalid file", "size", 10)
+	l.Infof("loaded %d files", 3)
+	l.Errorw("read failed", "error", errors.New("EOF"))
+
+	assert.Equal(t, 3, logs.Len())
+	logs.AssertMessages(t, "invalid file", "loaded 3 files", "read failed")
+	logs.AssertLogged(t, logger.WarnLevel, "invalid file", "path", "/data", "size", 10)
+	logs.AssertLogged(t, logger.ErrorLevel, "read failed", "error", errors.New("EOF"))
+
+	logs.FilterLevel(logger.InfoLevel).AssertLen(t, 1)
+	logs.FilterLoggerName("host").FilterField("size", 10).AssertLen(t, 1)
+	logs.FilterMessageSnippet("files").FilterFieldKey("path").AssertEmpty(t)
+
+	entry := logs.FilterMessage("invalid file").All()[0]
+	assert.Equal(t, "loggertest_test.go", filepath.Base(entry.Caller.File))
+
+	assert.Len(t, logs.TakeAll(), 3)
+	logs.AssertEmpty(t)
+
+	l.SetLevel(logger.WarnLevel)
+	l.Info("filtered")
+	logs.AssertEmpty(t)
+}
+
+func TestAssertFailures(t *testing.T) {
+	l, logs := New()
+	l.Infow("hello", "user", "admin")
+
+	ft := &fakeT{TB: t}
+	assert.False(t, logs.AssertLen(ft, 2))
+	assert.False(t, logs.AssertLogged(ft, logger.InfoLevel, "hello", "user", "guest"))
+	assert.False(t, logs.AssertLogged(ft, logger.InfoLevel, "hello", "user"))
+	assert.False(t, logs.AssertMessages(ft, "bye"))
+	assert.True(t, logs.AssertLogged(ft, logger.InfoLevel, "hello", "user", "admin"))
+
+	if assert.Len(t, ft.errors, 4) {
+		assert.Equal(t, "expected 2 log entries, got 1:\n\tINFO\thello\tuser=admin\n", ft.errors[0])
+		assert.Equal(t, `expected messages ["bye"], got ["hello"]`, ft.errors[3])
+	}
+}
+
+func TestObserveStandard(t *testing.T) {
+	derived := logger.With("component", "test")
+
+	t.Run("observed", func(t *testing.T) {
+		logs := ObserveStandard(t)
+		logger.Warn("from std")
+		derived.Info("from derived")
+
+		logs.AssertLogged(t, logger.WarnLevel, "from std")
+		logs.AssertLogged(t, logger.InfoLevel, "from derived", "component", "test")
+	})
+
+	// 测试结束后恢复，不再记录
+	l, logs := New()
+	restore := logger.ReplaceStandardLogger(l)
+	restore()
+	logger.Info("after restore")
+	logs.AssertEmpty(t)
+}
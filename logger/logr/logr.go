@@ -0,0 +1,99 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+// Package logr adapts logger.Logger to github.com/go-logr/logr, e.g. for
+// controller-runtime:
+//
+//	ctrl.SetLogger(bklogr.NewLogger(logger.StandardLogger()))
+package logr
+
+import (
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/TencentBlueKing/bkmonitor-kits/logger"
+)
+
+// LogSink implements logr.LogSink and logr.CallDepthLogSink on top of
+// logger.Logger. V(0) is logged at info level and higher verbosity at debug
+// level. Names added by WithName are joined with "." like logger.Named, so
+// they can be tuned with Options.Levels.
+type LogSink struct {
+	sugared *zap.SugaredLogger
+}
+
+var (
+	_ logr.LogSink          = (*LogSink)(nil)
+	_ logr.CallDepthLogSink = (*LogSink)(nil)
+)
+
+// NewLogger returns a logr.Logger writing to l.
+func NewLogger(l logger.Logger) logr.Logger {
+	return logr.New(NewLogSink(l))
+}
+
+// NewLogSink returns a logr.LogSink writing to l.
+func NewLogSink(l logger.Logger) *LogSink {
+	// 跳过 LogSink 自身的调用
+	return &LogSink{sugared: l.Desugar().WithOptions(zap.AddCallerSkip(1)).Sugar()}
+}
+
+// Init implements logr.LogSink, it skips the frames added by logr.Logger.
+func (s *LogSink) Init(info logr.RuntimeInfo) {
+	s.sugared = s.withCallDepth(info.CallDepth)
+}
+
+// Enabled implements logr.LogSink.
+func (s *LogSink) Enabled(level int) bool {
+	return s.sugared.Desugar().Core().Enabled(zapLevel(level))
+}
+
+// Info implements logr.LogSink.
+func (s *LogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if level > 0 {
+		s.sugared.Debugw(msg, keysAndValues...)
+		return
+	}
+	s.sugared.Infow(msg, keysAndValues...)
+}
+
+// Error implements logr.LogSink, err is logged under the "error" key.
+func (s *LogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	args := make([]interface{}, 0, len(keysAndValues)+1)
+	args = append(args, zap.Error(err))
+	s.sugared.Errorw(msg, append(args, keysAndValues...)...)
+}
+
+// WithValues implements logr.LogSink.
+func (s *LogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &LogSink{sugared: s.sugared.With(keysAndValues...)}
+}
+
+// WithName implements logr.LogSink.
+func (s *LogSink) WithName(name string) logr.LogSink {
+	return &LogSink{sugared: s.sugared.Named(name)}
+}
+
+// WithCallDepth implements logr.CallDepthLogSink.
+func (s *LogSink) WithCallDepth(depth int) logr.LogSink {
+	return &LogSink{sugared: s.withCallDepth(depth)}
+}
+
+func (s *LogSink) withCallDepth(depth int) *zap.SugaredLogger {
+	return s.sugared.Desugar().WithOptions(zap.AddCallerSkip(depth)).Sugar()
+}
+
+func zapLevel(level int) zapcore.Level {
+	if level > 0 {
+		return zapcore.DebugLevel
+	}
+	return zapcore.InfoLevel
+}
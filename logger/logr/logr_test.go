@@ -0,0 +1,69 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logr
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-kits/logger"
+	"github.com/TencentBlueKing/bkmonitor-kits/logger/loggertest"
+)
+
+func TestLogSink(t *testing.T) {
+	l, logs := loggertest.New()
+	l.SetLevel(logger.InfoLevel)
+	log := NewLogger(l).WithName("controller").WithValues("kind", "Pod")
+
+	log.Info("reconciling", "name", "web-0")
+	log.V(1).Info("verbose")
+	log.WithName("pod").Error(errors.New("not found"), "reconcile failed", "requeue", true)
+
+	logs.AssertMessages(t, "reconciling", "reconcile failed")
+	logs.AssertLogged(t, logger.InfoLevel, "reconciling", "kind", "Pod", "name", "web-0")
+	logs.AssertLogged(t, logger.ErrorLevel, "reconcile failed", "kind", "Pod", "error", errors.New("not found"), "requeue", true)
+	logs.FilterLoggerName("controller").AssertLen(t, 1)
+	logs.FilterLoggerName("controller.pod").AssertLen(t, 1)
+
+	for _, e := range logs.All() {
+		assert.Equal(t, "logr_test.go", filepath.Base(e.Caller.File))
+	}
+
+	assert.False(t, log.V(1).Enabled())
+	l.SetLevel(logger.DebugLevel)
+	assert.True(t, log.V(2).Enabled())
+	log.V(2).Info("verbose")
+	logs.FilterLevel(logger.DebugLevel).AssertLen(t, 1)
+}
+
+// helper 模拟封装了 logr 的辅助函数
+func helper(log interface{ Info(string, ...interface{}) }) {
+	log.Info("from helper")
+}
+
+func TestLogSinkCallDepth(t *testing.T) {
+	l, logs := loggertest.New()
+	log := NewLogger(l)
+
+	_, _, line, _ := runtime.Caller(0)
+	helper(log.WithCallDepth(1))
+	line++
+
+	entries := logs.All()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "logr_test.go", filepath.Base(entries[0].Caller.File))
+		assert.Equal(t, line, entries[0].Caller.Line)
+	}
+}
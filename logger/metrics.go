@@ -0,0 +1,228 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// MetricsOptions is the option set for the instrumentation of Logger.
+type MetricsOptions struct {
+	// Namespace is prepended to the names of the exported metrics, e.g.
+	// "bkmonitorbeat" gives bkmonitorbeat_logger_entries_total. Empty means
+	// no prefix.
+	Namespace string `yaml:"namespace"`
+}
+
+// Stats is a snapshot of the counters of a Logger. The counters are kept
+// across Reload and only grow while Options.Metrics is set.
+type Stats struct {
+	// Entries is the number of entries written, keyed by level name.
+	Entries map[string]uint64
+
+	// NamedEntries is the number of entries written by named loggers, keyed
+	// by logger name and level name. It is a subset of Entries.
+	NamedEntries map[string]map[string]uint64
+
+	// BytesWritten is the number of bytes written by all sinks.
+	BytesWritten uint64
+
+	// WriteErrors is the number of failed writes of all sinks.
+	WriteErrors uint64
+}
+
+// Stats returns the counters of the logger.
+func (l Logger) Stats() Stats {
+	return l.root.load().state.metrics.snapshot()
+}
+
+// MetricsHandler returns an http.Handler which exposes the counters of l in
+// the Prometheus text format.
+func (l Logger) MetricsHandler() http.Handler {
+	return &metricsHandler{logger: func() Logger { return l }}
+}
+
+// MetricsHandler returns an http.Handler which exposes the counters of the
+// standard logger in the Prometheus text format. It keeps working after
+// SetOptions.
+func MetricsHandler() http.Handler {
+	return &metricsHandler{logger: StandardLogger}
+}
+
+// metrics 按 logger 名称和级别统计日志数量，以及所有输出写入的字节数和失败次数
+type metrics struct {
+	entries sync.Map // entryKey -> *uint64
+	bytes   uint64
+	errors  uint64
+
+	namespace atomic.Value // string
+}
+
+type entryKey struct {
+	name  string
+	level zapcore.Level
+}
+
+func (m *metrics) countEntry(name string, level zapcore.Level) {
+	key := entryKey{name: name, level: level}
+	v, ok := m.entries.Load(key)
+	if !ok {
+		v, _ = m.entries.LoadOrStore(key, new(uint64))
+	}
+	atomic.AddUint64(v.(*uint64), 1)
+}
+
+func (m *metrics) countWrite(n int, err error) {
+	atomic.AddUint64(&m.bytes, uint64(n))
+	if err != nil {
+		atomic.AddUint64(&m.errors, 1)
+	}
+}
+
+func (m *metrics) snapshot() Stats {
+	s := Stats{
+		Entries:      make(map[string]uint64),
+		NamedEntries: make(map[string]map[string]uint64),
+		BytesWritten: atomic.LoadUint64(&m.bytes),
+		WriteErrors:  atomic.LoadUint64(&m.errors),
+	}
+	m.entries.Range(func(k, v interface{}) bool {
+		key := k.(entryKey)
+		n := atomic.LoadUint64(v.(*uint64))
+		level := key.level.String()
+		s.Entries[level] += n
+		if key.name != "" {
+			if s.NamedEntries[key.name] == nil {
+				s.NamedEntries[key.name] = make(map[string]uint64)
+			}
+			s.NamedEntries[key.name][level] += n
+		}
+		return true
+	})
+	return s
+}
+
+// metricsCore 统计实际写入的日志，位于采样之后，被采样丢弃的日志不计数
+type metricsCore struct {
+	zapcore.Core
+	m *metrics
+}
+
+func (c *metricsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &metricsCore{Core: c.Core.With(fields), m: c.m}
+}
+
+func (c *metricsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Core.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce).AddCore(ent, entryCounter{c.m})
+}
+
+// entryCounter 仅用于在写入时计数
+type entryCounter struct {
+	m *metrics
+}
+
+func (c entryCounter) Enabled(zapcore.Level) bool        { return true }
+func (c entryCounter) With([]zapcore.Field) zapcore.Core { return c }
+func (c entryCounter) Sync() error                       { return nil }
+
+func (c entryCounter) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c entryCounter) Write(ent zapcore.Entry, _ []zapcore.Field) error {
+	c.m.countEntry(ent.LoggerName, ent.Level)
+	return nil
+}
+
+// countingWriter 统计底层输出的写入字节数和失败次数，位于异步队列之后
+type countingWriter struct {
+	zapcore.WriteSyncer
+	m *metrics
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	w.m.countWrite(n, err)
+	return n, err
+}
+
+type metricsHandler struct {
+	logger func() Logger
+}
+
+func (h *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	gen := h.logger().root.load()
+	ns, _ := gen.state.metrics.namespace.Load().(string)
+
+	var b bytes.Buffer
+	writeMetrics(&b, ns, &gen.state.metrics)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(b.Bytes())
+}
+
+// writeMetrics 按 Prometheus 文本格式输出，顺序固定便于比较
+func writeMetrics(b *bytes.Buffer, namespace string, m *metrics) {
+	prefix := "logger_"
+	if namespace != "" {
+		prefix = namespace + "_" + prefix
+	}
+
+	type sample struct {
+		key entryKey
+		n   uint64
+	}
+	var samples []sample
+	m.entries.Range(func(k, v interface{}) bool {
+		samples = append(samples, sample{key: k.(entryKey), n: atomic.LoadUint64(v.(*uint64))})
+		return true
+	})
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].key.name != samples[j].key.name {
+			return samples[i].key.name < samples[j].key.name
+		}
+		return samples[i].key.level < samples[j].key.level
+	})
+
+	fmt.Fprintf(b, "# HELP %sentries_total Number of log entries written, by logger name and level.\n", prefix)
+	fmt.Fprintf(b, "# TYPE %sentries_total counter\n", prefix)
+	for _, s := range samples {
+		fmt.Fprintf(b, "%sentries_total{logger=\"%s\",level=\"%s\"} %d\n",
+			prefix, escapeLabel(s.key.name), s.key.level.String(), s.n)
+	}
+	fmt.Fprintf(b, "# HELP %sbytes_written_total Number of bytes written by all sinks.\n", prefix)
+	fmt.Fprintf(b, "# TYPE %sbytes_written_total counter\n", prefix)
+	fmt.Fprintf(b, "%sbytes_written_total %d\n", prefix, atomic.LoadUint64(&m.bytes))
+	fmt.Fprintf(b, "# HELP %swrite_errors_total Number of failed writes of all sinks.\n", prefix)
+	fmt.Fprintf(b, "# TYPE %swrite_errors_total counter\n", prefix)
+	fmt.Fprintf(b, "%swrite_errors_total %d\n", prefix, atomic.LoadUint64(&m.errors))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,108 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerStats(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	l := New(Options{
+		Filename: filename,
+		Metrics:  &MetricsOptions{},
+		Sampling: &SamplingOptions{Tick: time.Minute, Initial: 1, Thereafter: 100},
+	})
+	defer l.Close()
+
+	l.Info("started")
+	l.Debug("filtered")
+	l.Named("host").Warn("invalid file")
+	l.Named("host").Warn("invalid file")
+	l.Named("host.watcher").Error("failed")
+
+	info, err := os.Stat(filename)
+	assert.NoError(t, err)
+	// 被级别过滤和被采样丢弃的日志不计数
+	assert.Equal(t, Stats{
+		Entries: map[string]uint64{"info": 1, "warn": 1, "error": 1},
+		NamedEntries: map[string]map[string]uint64{
+			"host":         {"warn": 1},
+			"host.watcher": {"error": 1},
+		},
+		BytesWritten: uint64(info.Size()),
+	}, l.Stats())
+
+	// 热加载后计数保留
+	assert.NoError(t, l.Reload(Options{Filename: filename, Metrics: &MetricsOptions{}}))
+	l.Info("reloaded")
+	assert.Equal(t, uint64(2), l.Stats().Entries["info"])
+
+	// 关闭统计后不再计数
+	assert.NoError(t, l.Reload(Options{Filename: filename}))
+	l.Info("disabled")
+	assert.Equal(t, uint64(2), l.Stats().Entries["info"])
+}
+
+type failingSyncer struct{}
+
+func (failingSyncer) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+func (failingSyncer) Sync() error               { return nil }
+
+func TestCountingWriter(t *testing.T) {
+	m := new(metrics)
+	w := countingWriter{WriteSyncer: failingSyncer{}, m: m}
+	_, err := w.Write([]byte("hello"))
+	assert.Error(t, err)
+	assert.Equal(t, Stats{Entries: map[string]uint64{}, NamedEntries: map[string]map[string]uint64{}, WriteErrors: 1}, m.snapshot())
+}
+
+func TestMetricsHandler(t *testing.T) {
+	l := New(Options{
+		Filename: filepath.Join(t.TempDir(), "app.log"),
+		Metrics:  &MetricsOptions{Namespace: "bkmonitorbeat"},
+	})
+	defer l.Close()
+	l.Info("started")
+	l.Named(`odd"name`).Warn("invalid file")
+	written := l.Stats().BytesWritten
+
+	rec := httptest.NewRecorder()
+	l.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	body, _ := ioutil.ReadAll(rec.Body)
+	assert.Equal(t, `# HELP bkmonitorbeat_logger_entries_total Number of log entries written, by logger name and level.
+# TYPE bkmonitorbeat_logger_entries_total counter
+bkmonitorbeat_logger_entries_total{logger="",level="info"} 1
+bkmonitorbeat_logger_entries_total{logger="odd\"name",level="warn"} 1
+# HELP bkmonitorbeat_logger_bytes_written_total Number of bytes written by all sinks.
+# TYPE bkmonitorbeat_logger_bytes_written_total counter
+bkmonitorbeat_logger_bytes_written_total `+strconv.FormatUint(written, 10)+`
+# HELP bkmonitorbeat_logger_write_errors_total Number of failed writes of all sinks.
+# TYPE bkmonitorbeat_logger_write_errors_total counter
+bkmonitorbeat_logger_write_errors_total 0
+`, string(body))
+
+	rec = httptest.NewRecorder()
+	l.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,135 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Named adds a sub-scope to the logger's name. Names are joined by periods,
+// so l.Named("register").Named("consul") is named "register.consul". The
+// name is written under the encoder's name key and is matched against
+// Options.Levels.
+func (l Logger) Named(name string) Logger {
+	l.sugared = l.sugared.Named(name)
+	return l
+}
+
+// Named adds a sub-scope to the standard logger's name.
+func Named(name string) Logger {
+	return std.Named(name)
+}
+
+// levelCore 根据 logger 名称决定日志级别，未匹配任何前缀时使用 logger 的全局级别
+type levelCore struct {
+	zapcore.Core
+	level     zap.AtomicLevel
+	overrides map[string]zapcore.Level
+	min       zapcore.Level
+
+	// 缓存名称到覆盖级别的匹配结果，logger 名称的数量通常很少
+	cache *sync.Map
+}
+
+type nameLevel struct {
+	level zapcore.Level
+	ok    bool
+}
+
+// newLevelCore 解析 Options.Levels，前缀与级别非法时返回错误
+func newLevelCore(core zapcore.Core, level zap.AtomicLevel, levels map[string]string) (*levelCore, error) {
+	c := &levelCore{
+		Core:      core,
+		level:     level,
+		overrides: make(map[string]zapcore.Level, len(levels)),
+		min:       zapcore.FatalLevel,
+		cache:     new(sync.Map),
+	}
+	for prefix, text := range levels {
+		lvl, err := ParseLevel(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level for logger %q: %w", prefix, err)
+		}
+		prefix = strings.Trim(prefix, ".")
+		if prefix == "" {
+			return nil, fmt.Errorf("empty logger name in levels")
+		}
+		c.overrides[prefix] = zapcore.Level(lvl)
+		if zapcore.Level(lvl) < c.min {
+			c.min = zapcore.Level(lvl)
+		}
+	}
+	return c, nil
+}
+
+// Enabled 只要全局级别或任一覆盖级别允许即返回 true，具体的过滤在 Check 中按名称进行
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	if !c.level.Enabled(lvl) && (len(c.overrides) == 0 || lvl < c.min) {
+		return false
+	}
+	return c.Core.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{
+		Core:      c.Core.With(fields),
+		level:     c.level,
+		overrides: c.overrides,
+		min:       c.min,
+		cache:     c.cache,
+	}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabledFor(ent) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// enabledFor 按 logger 名称判断日志是否输出
+func (c *levelCore) enabledFor(ent zapcore.Entry) bool {
+	if lvl, ok := c.levelFor(ent.LoggerName); ok {
+		return ent.Level >= lvl
+	}
+	return c.level.Enabled(ent.Level)
+}
+
+// levelFor 返回与名称匹配的最长前缀的级别，前缀需以 "." 为边界
+func (c *levelCore) levelFor(name string) (zapcore.Level, bool) {
+	if len(c.overrides) == 0 || name == "" {
+		return 0, false
+	}
+	if v, ok := c.cache.Load(name); ok {
+		nl := v.(nameLevel)
+		return nl.level, nl.ok
+	}
+
+	var nl nameLevel
+	for key := name; ; {
+		if lvl, ok := c.overrides[key]; ok {
+			nl = nameLevel{level: lvl, ok: true}
+			break
+		}
+		i := strings.LastIndexByte(key, '.')
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	c.cache.Store(name, nl)
+	return nl.level, nl.ok
+}
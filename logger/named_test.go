@@ -0,0 +1,83 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerNamedLevels(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "named.log")
+	l := New(Options{
+		Level:    "info",
+		Filename: filename,
+		Levels: map[string]string{
+			"host":            "warn",
+			"register.consul": "debug",
+		},
+	})
+	defer l.Close()
+
+	l.Debug("root debug")
+	l.Info("root info")
+	l.Named("host").Info("host info")
+	l.Named("host").Named("watcher").Warn("host watcher warn")
+	l.Named("hostname").Info("hostname info")
+	l.Named("register").Debug("register debug")
+	l.Named("register").Named("consul").Debug("consul debug")
+	l.Named("register").Named("consul").With("service", "bkmonitor").Named("keepalive").Debug("keepalive debug")
+
+	b, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if !assert.Len(t, lines, 5) {
+		return
+	}
+	assert.Contains(t, lines[0], `msg="root info"`)
+	assert.Contains(t, lines[1], `logger=host.watcher`)
+	assert.Contains(t, lines[1], `msg="host watcher warn"`)
+	assert.Contains(t, lines[2], `logger=hostname`)
+	assert.Contains(t, lines[3], `logger=register.consul`)
+	assert.Contains(t, lines[3], `msg="consul debug"`)
+	assert.Contains(t, lines[4], `logger=register.consul.keepalive`)
+	assert.Contains(t, lines[4], `service=bkmonitor`)
+}
+
+func TestLoggerNamedLevelsFollowSetLevel(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "named.log")
+	l := New(Options{
+		Level:    "info",
+		Filename: filename,
+		Levels:   map[string]string{"host": "warn"},
+	})
+	defer l.Close()
+
+	l.SetLevel(ErrorLevel)
+	l.Warn("root warn")
+	l.Named("host").Warn("host warn")
+
+	b, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `msg="host warn"`)
+}
+
+func TestLoggerInvalidNamedLevel(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Options{Stdout: true, Levels: map[string]string{"host": "verbose"}})
+	})
+}
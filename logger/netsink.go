@@ -0,0 +1,402 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	defaultSpillMaxSize = 100 // megabytes
+
+	netQueueSize       = 4 * 1024 * 1024
+	spillChunkSize     = 64 * 1024
+	spillDrainInterval = time.Second
+)
+
+// NetOptions is the option set for the net sink, which ships encoded entries
+// to a log collector, one entry per line.
+type NetOptions struct {
+	// Network is the transport, Valid values are "tcp" and "unix".
+	Network string `yaml:"network"`
+
+	// Address is host:port for tcp, or the socket path for unix.
+	Address string `yaml:"address"`
+
+	// SpillFile buffers entries on disk while the collector is unreachable
+	// or too slow, they are sent in order once the connection is back,
+	// including those left by a previous run. Entries not sent yet are kept
+	// on Close, those partly sent before the process crashes may be sent
+	// again. Empty means entries are dropped instead.
+	SpillFile string `yaml:"spill_file"`
+
+	// SpillMaxSize is the maximum size in megabytes of SpillFile, entries
+	// beyond it are dropped. Default is 100.
+	SpillMaxSize int `yaml:"spill_max_size"`
+}
+
+func (opt NetOptions) validate(prefix string) error {
+	var errs error
+	switch opt.Network {
+	case "tcp", "unix":
+	default:
+		errs = multierr.Append(errs, fmt.Errorf("%snet.network: unknown network %q", prefix, opt.Network))
+	}
+	if opt.Address == "" {
+		errs = multierr.Append(errs, fmt.Errorf("%snet.address: required", prefix))
+	}
+	if opt.SpillMaxSize < 0 {
+		errs = multierr.Append(errs, fmt.Errorf("%snet.spill_max_size: must not be negative", prefix))
+	}
+	if opt.SpillFile != "" {
+		if err := checkWritable(opt.SpillFile); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("%snet.spill_file: %w", prefix, err))
+		}
+	}
+	return errs
+}
+
+// NetStats is the traffic of the net sinks of a Logger, counted since the
+// last Reload.
+type NetStats struct {
+	// BytesSent is the number of bytes written to the collectors. An entry
+	// cut by a broken connection is sent again in whole after reconnecting,
+	// so its first part is counted twice.
+	BytesSent uint64
+
+	// BytesDropped is the number of bytes discarded because a collector was
+	// unreachable or too slow and the spill file was not configured or full.
+	BytesDropped uint64
+
+	// BytesSpilled is the number of bytes written to the spill files.
+	BytesSpilled uint64
+}
+
+// NetStats returns the traffic of all net sinks of the logger.
+func (l Logger) NetStats() NetStats {
+	var s NetStats
+	for _, w := range l.root.load().res.netWriters {
+		s.BytesSent += atomic.LoadUint64(&w.sent)
+		s.BytesDropped += atomic.LoadUint64(&w.dropped)
+		s.BytesSpilled += atomic.LoadUint64(&w.spilled)
+	}
+	return s
+}
+
+// netWriter 将日志发送到采集器，Write 只把日志放入内存队列，由后台 goroutine 发送，不会阻塞在网络上。
+// 未发送的日志先在内存队列中，再在溢出文件中：连接失败或者队列已满时之后的日志追加到溢出文件，
+// 后台 goroutine 先发送队列再从溢出文件中按顺序补发，追上之后清空溢出文件
+type netWriter struct {
+	conn      *redialConn
+	spillPath string
+	spillMax  int64
+
+	sent    uint64
+	dropped uint64
+	spilled uint64
+
+	mut      sync.Mutex
+	queue    [][]byte // 等待发送的日志，只有后台 goroutine 从头部移除
+	queued   int64    // queue 中的字节数
+	spill    *os.File
+	size     int64 // 溢出文件的长度
+	offset   int64 // 已经补发的位置
+	entry    int64 // 正在补发的日志在溢出文件中的起始位置，发送失败时从这里整条重发
+	spilling bool  // 为 true 时新的日志追加到溢出文件
+	closed   bool
+
+	closeOnce sync.Once
+	notify    chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+func newNetWriter(opt NetOptions) (*netWriter, error) {
+	w := &netWriter{
+		conn:    newRedialConn(opt.Network, opt.Address),
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if opt.SpillFile != "" {
+		if err := os.MkdirAll(filepath.Dir(opt.SpillFile), os.ModePerm); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(opt.SpillFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		maxSize := opt.SpillMaxSize
+		if maxSize == 0 {
+			maxSize = defaultSpillMaxSize
+		}
+		w.spill = f
+		w.spillPath = opt.SpillFile
+		w.spillMax = int64(maxSize) * 1024 * 1024
+		// 上次退出时未补发完的日志
+		w.size = info.Size()
+		w.spilling = w.size > 0
+	}
+	go w.run()
+	if w.spilling {
+		w.wakeup()
+	}
+	return w, nil
+}
+
+// Write implements io.Writer, it only queues p and never waits on the
+// network. The entries are never reported as failed to avoid flooding
+// ErrorOutput during an outage, see NetStats instead.
+func (w *netWriter) Write(p []byte) (int, error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	switch {
+	case w.closed:
+		atomic.AddUint64(&w.dropped, uint64(len(p)))
+	case w.spilling:
+		w.spillLocked(p)
+	case w.queued+int64(len(p)) > netQueueSize:
+		// 采集器跟不上，之后的日志排在队列后面
+		w.spillLocked(p)
+	default:
+		b := make([]byte, len(p))
+		copy(b, p)
+		w.queue = append(w.queue, b)
+		w.queued += int64(len(b))
+		w.wakeup()
+	}
+	return len(p), nil
+}
+
+func (w *netWriter) spillLocked(p []byte) {
+	if w.spill == nil || w.size+int64(len(p)) > w.spillMax {
+		atomic.AddUint64(&w.dropped, uint64(len(p)))
+		return
+	}
+	if _, err := w.spill.Write(p); err != nil {
+		// 去掉只写入了一部分的日志，溢出文件中只保留完整的行
+		w.spill.Truncate(w.size)
+		atomic.AddUint64(&w.dropped, uint64(len(p)))
+		return
+	}
+	w.size += int64(len(p))
+	atomic.AddUint64(&w.spilled, uint64(len(p)))
+	if !w.spilling {
+		w.spilling = true
+		w.wakeup()
+	}
+}
+
+func (w *netWriter) wakeup() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Sync implements zapcore.WriteSyncer
+func (w *netWriter) Sync() error {
+	return nil
+}
+
+// Close makes a last attempt to send the queued entries and closes the
+// connection, entries not sent yet are kept in the spill file for the next
+// run.
+func (w *netWriter) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		<-w.stopped
+
+		w.mut.Lock()
+		defer w.mut.Unlock()
+		w.closed = true
+		err = w.conn.Close()
+		if w.spill != nil {
+			err = multierr.Append(err, w.persistLocked())
+			err = multierr.Append(err, w.spill.Close())
+		} else if w.queued > 0 {
+			atomic.AddUint64(&w.dropped, uint64(w.queued))
+		}
+		w.queue, w.queued = nil, 0
+	})
+	return err
+}
+
+// persistLocked 将队列中和溢出文件中尚未发送的日志按顺序写回溢出文件，已经补发的部分不再保留
+func (w *netWriter) persistLocked() error {
+	if !w.spilling && len(w.queue) == 0 {
+		return w.spill.Truncate(0)
+	}
+	if w.offset == 0 && len(w.queue) == 0 {
+		return nil
+	}
+
+	tmp := w.spillPath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, b := range w.queue {
+		if _, err = f.Write(b); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		_, err = io.Copy(f, io.NewSectionReader(w.spill, w.offset, w.size-w.offset))
+	}
+	err = multierr.Append(err, f.Close())
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, w.spillPath)
+}
+
+func (w *netWriter) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(spillDrainInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			// 退出前尽量发送队列中的日志，溢出文件留给下次启动
+			w.send(true)
+			return
+		case <-w.notify:
+		case <-ticker.C:
+		}
+		w.send(false)
+	}
+}
+
+// send 先发送队列中的日志，再从溢出文件中补发，直到全部发送或者连接失败
+func (w *netWriter) send(queueOnly bool) {
+	buf := make([]byte, spillChunkSize)
+	for {
+		w.mut.Lock()
+		fromQueue := len(w.queue) > 0
+		var batch []byte
+		var offset, size int64
+		switch {
+		case fromQueue:
+			batch = w.batchLocked(buf[:0])
+		case queueOnly || !w.spilling:
+			w.mut.Unlock()
+			return
+		case w.offset >= w.size:
+			// 已经追上，之后的日志直接进入队列
+			if err := w.spill.Truncate(0); err == nil {
+				w.size, w.offset, w.entry = 0, 0, 0
+				w.spilling = false
+			}
+			w.mut.Unlock()
+			return
+		default:
+			offset, size = w.offset, w.size
+		}
+		w.mut.Unlock()
+
+		if !fromQueue {
+			// 补发时不持有锁，新日志可以继续追加到溢出文件
+			chunk := buf
+			if size-offset < int64(len(chunk)) {
+				chunk = chunk[:size-offset]
+			}
+			n, _ := w.spill.ReadAt(chunk, offset)
+			if n == 0 {
+				return
+			}
+			batch = chunk[:n]
+		}
+
+		n, err := w.conn.Write(batch)
+		atomic.AddUint64(&w.sent, uint64(n))
+
+		w.mut.Lock()
+		if fromQueue {
+			w.ackLocked(n)
+		} else {
+			if i := bytes.LastIndexByte(batch[:n], '\n'); i >= 0 {
+				w.entry = offset + int64(i) + 1
+			}
+			w.offset += int64(n)
+			if err != nil {
+				w.offset = w.entry
+			}
+		}
+		if err != nil {
+			w.failLocked()
+		}
+		w.mut.Unlock()
+		if err != nil {
+			return
+		}
+
+		select {
+		case <-w.done:
+			if !queueOnly {
+				return
+			}
+		default:
+		}
+	}
+}
+
+// batchLocked 从队列头部取出不超过 spillChunkSize 的日志（至少一条）拼接到 buf
+func (w *netWriter) batchLocked(buf []byte) []byte {
+	for i, b := range w.queue {
+		if i > 0 && len(buf)+len(b) > spillChunkSize {
+			break
+		}
+		buf = append(buf, b...)
+	}
+	return buf
+}
+
+// ackLocked 从队列头部移除已经完整发送的日志。只有发送失败时才会发送一部分，这条日志整条留在队列中，
+// 之后整条转存到溢出文件或者重连后重发，采集器不会收到只有后半部分的行
+func (w *netWriter) ackLocked(n int) {
+	for len(w.queue) > 0 && n >= len(w.queue[0]) {
+		n -= len(w.queue[0])
+		w.queued -= int64(len(w.queue[0]))
+		w.queue[0] = nil
+		w.queue = w.queue[1:]
+	}
+}
+
+// failLocked 处理发送失败：队列中的日志转存到溢出文件，没有配置溢出文件时丢弃。
+// 已经在写溢出文件时队列中的日志排在溢出文件之前，只能留在内存中等待重连
+func (w *netWriter) failLocked() {
+	if w.spilling {
+		return
+	}
+	for _, b := range w.queue {
+		w.spillLocked(b)
+	}
+	w.queue, w.queued = nil, 0
+}
@@ -0,0 +1,312 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// collectLines 接收 listener 上所有连接发送的日志行
+func collectLines(ln net.Listener) <-chan string {
+	lines := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	return lines
+}
+
+func receiveLine(t *testing.T, lines <-chan string) string {
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("no line received")
+		return ""
+	}
+}
+
+func TestLoggerNetTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ln.Close()
+	lines := collectLines(ln)
+
+	l := New(Options{Sinks: []SinkOptions{{
+		Type:   SinkNet,
+		Format: "json",
+		Net:    NetOptions{Network: "tcp", Address: ln.Addr().String()},
+	}}})
+	defer l.Close()
+
+	l.Info("first")
+	l.Info("second")
+	assert.Contains(t, receiveLine(t, lines), `"msg":"first"`)
+	assert.Contains(t, receiveLine(t, lines), `"msg":"second"`)
+
+	stats := l.NetStats()
+	assert.NotZero(t, stats.BytesSent)
+	assert.Zero(t, stats.BytesDropped)
+	assert.Zero(t, stats.BytesSpilled)
+}
+
+func TestLoggerNetSpill(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket is not supported on windows")
+	}
+	dir := t.TempDir()
+	addr := filepath.Join(dir, "collector.sock")
+	spill := filepath.Join(dir, "spill", "net.spill")
+
+	l := New(Options{Sinks: []SinkOptions{{
+		Type: SinkNet,
+		Net:  NetOptions{Network: "unix", Address: addr, SpillFile: spill},
+	}}})
+	defer l.Close()
+
+	// 采集器未启动，发送失败后日志转存到溢出文件
+	for _, msg := range []string{"a", "b", "c"} {
+		l.Info(msg)
+	}
+	var b []byte
+	assert.Eventually(t, func() bool {
+		b, _ = ioutil.ReadFile(spill)
+		return strings.Count(string(b), "\n") == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(len(b)), l.NetStats().BytesSpilled)
+
+	ln, err := net.Listen("unix", addr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ln.Close()
+	lines := collectLines(ln)
+
+	// 重连后按顺序补发，补发完成前的新日志排在后面
+	l.Info("d")
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		assert.Contains(t, receiveLine(t, lines), "msg="+msg)
+	}
+	assert.Eventually(t, func() bool {
+		b, _ := ioutil.ReadFile(spill)
+		return len(b) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	l.Info("e")
+	assert.Contains(t, receiveLine(t, lines), "msg=e")
+	assert.Zero(t, l.NetStats().BytesDropped)
+}
+
+func TestLoggerNetSpillFromPreviousRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket is not supported on windows")
+	}
+	dir := t.TempDir()
+	addr := filepath.Join(dir, "collector.sock")
+	spill := filepath.Join(dir, "net.spill")
+	assert.NoError(t, ioutil.WriteFile(spill, []byte("msg=left\n"), 0o644))
+
+	ln, err := net.Listen("unix", addr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ln.Close()
+	lines := collectLines(ln)
+
+	l := New(Options{Sinks: []SinkOptions{{
+		Type: SinkNet,
+		Net:  NetOptions{Network: "unix", Address: addr, SpillFile: spill},
+	}}})
+	defer l.Close()
+
+	assert.Equal(t, "msg=left", receiveLine(t, lines))
+	l.Info("new")
+	assert.Contains(t, receiveLine(t, lines), "msg=new")
+}
+
+func TestLoggerNetDropped(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	l := New(Options{Sinks: []SinkOptions{{
+		Type: SinkNet,
+		Net:  NetOptions{Network: "tcp", Address: addr},
+	}}})
+	defer l.Close()
+
+	l.Info("lost")
+	assert.Eventually(t, func() bool { return l.NetStats().BytesDropped > 0 }, 5*time.Second, 10*time.Millisecond)
+	assert.Zero(t, l.NetStats().BytesSent)
+}
+
+func TestNetWriterSpillFull(t *testing.T) {
+	w, err := newNetWriter(NetOptions{Network: "unix", Address: filepath.Join(t.TempDir(), "none.sock"), SpillFile: filepath.Join(t.TempDir(), "net.spill")})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+
+	w.mut.Lock()
+	w.spillMax = 10
+	w.mut.Unlock()
+
+	w.Write([]byte("12345678\n"))
+	w.Write([]byte("overflow\n"))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadUint64(&w.spilled) == 9 && atomic.LoadUint64(&w.dropped) == 9
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNetWriterWriteNotBlocked(t *testing.T) {
+	w, err := newNetWriter(NetOptions{Network: "tcp", Address: "127.0.0.1:0"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	// 采集器没有响应时建立连接一直阻塞，Write 不受影响
+	unblock := make(chan struct{})
+	w.conn = newRedialConnFunc(func() (net.Conn, error) {
+		<-unblock
+		return nil, errConnUnavailable
+	})
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		n, err := w.Write([]byte("entry\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, 6, n)
+	}
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	close(unblock)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, uint64(600), atomic.LoadUint64(&w.dropped))
+}
+
+func TestNetWriterCloseKeepsUnsent(t *testing.T) {
+	dir := t.TempDir()
+	spill := filepath.Join(dir, "net.spill")
+	assert.NoError(t, ioutil.WriteFile(spill, []byte("msg=a\nmsg=b\n"), 0o644))
+
+	w, err := newNetWriter(NetOptions{Network: "unix", Address: filepath.Join(dir, "none.sock"), SpillFile: spill})
+	if !assert.NoError(t, err) {
+		return
+	}
+	// 第一行已经补发，关闭后只保留未发送的部分
+	w.mut.Lock()
+	w.offset = int64(len("msg=a\n"))
+	w.entry = w.offset
+	w.mut.Unlock()
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "msg=b\n", readFile(t, spill))
+}
+
+// shortConn 只接受前 limit 个字节，之后的写入只写入一部分并返回错误，模拟写到一半断开的连接
+type shortConn struct {
+	net.Conn
+	mut   sync.Mutex
+	buf   strings.Builder
+	limit int
+}
+
+func (c *shortConn) Write(p []byte) (int, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	n := c.limit - c.buf.Len()
+	if n >= len(p) {
+		c.buf.Write(p)
+		return len(p), nil
+	}
+	c.buf.Write(p[:n])
+	return n, errors.New("broken pipe")
+}
+
+func (c *shortConn) String() string {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.buf.String()
+}
+
+func (c *shortConn) RemoteAddr() net.Addr             { return &net.UnixAddr{Net: "unix"} }
+func (c *shortConn) SetWriteDeadline(time.Time) error { return nil }
+func (c *shortConn) Close() error                     { return nil }
+
+func TestNetWriterPartialWrite(t *testing.T) {
+	dir := t.TempDir()
+	spill := filepath.Join(dir, "net.spill")
+	w, err := newNetWriter(NetOptions{Network: "unix", Address: filepath.Join(dir, "none.sock"), SpillFile: spill})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// 第一个连接在第二条日志中间断开，第二个连接在补发第三条日志时断开，之后无法再建立连接
+	conns := []*shortConn{{limit: 15}, {limit: 14}}
+	var dials int32
+	w.conn = newRedialConnFunc(func() (net.Conn, error) {
+		i := atomic.AddInt32(&dials, 1) - 1
+		if int(i) < len(conns) {
+			return conns[i], nil
+		}
+		return nil, errConnUnavailable
+	})
+
+	w.Write([]byte("msg=first\n"))
+	w.Write([]byte("msg=second\n"))
+	w.Write([]byte("msg=third\n"))
+	assert.Eventually(t, func() bool {
+		return int(atomic.LoadInt32(&dials)) > len(conns)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(t, w.Close())
+
+	// 断开时只发送了一部分的日志整条转存并从开头重发，溢出文件中只保留完整的行
+	assert.Equal(t, "msg=first\nmsg=s", conns[0].String())
+	assert.Equal(t, "msg=second\nmsg", conns[1].String())
+	assert.Equal(t, "msg=third\n", readFile(t, spill))
+	assert.Equal(t, uint64(0), atomic.LoadUint64(&w.dropped))
+}
+
+func TestOptionsValidateNet(t *testing.T) {
+	err := Options{Sinks: []SinkOptions{{
+		Type: SinkNet,
+		Net:  NetOptions{Network: "udp", SpillMaxSize: -1},
+	}}}.Validate()
+	assert.EqualError(t, err, `sinks[0].net.network: unknown network "udp"; `+
+		`sinks[0].net.address: required; `+
+		`sinks[0].net.spill_max_size: must not be negative`)
+}
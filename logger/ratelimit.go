@@ -0,0 +1,204 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// SuppressedKey is the field reporting how many entries were suppressed
+	// by Every since the last one logged.
+	SuppressedKey = "suppressed"
+
+	// 记录数的上限，达到时先清理已过期的记录，仍然超过时新的 key 共用同一条记录
+	maxRateLimitEntries = 4096
+
+	// 达到上限时清理的最小间隔，避免每条新的日志都遍历所有记录
+	rateLimitSweepInterval = time.Second
+)
+
+// Every returns a logger that writes each distinct message at most once per
+// interval. Entries are identified by level, logger name and message, so the
+// message must come from a constant template, put the variable parts in
+// fields: l.Every(time.Minute).Warnw("bad file", "path", path). The first
+// entry after a quiet period carries the number of entries suppressed in
+// between under the "suppressed" key. The state is shared by all loggers
+// derived from the same New call, so Every can be called inline. At most
+// 4096 keys are tracked, beyond that new messages share a single limit.
+func (l Logger) Every(interval time.Duration) Logger {
+	prefix := "every|" + strconv.FormatInt(int64(interval), 10) + "|"
+	return l.limit(func(ent zapcore.Entry) string {
+		return prefix + ent.Level.String() + "|" + ent.LoggerName + "|" + ent.Message
+	}, interval)
+}
+
+// Once returns a logger that writes only the first entry logged with key,
+// all later entries with the same key are dropped. key must be a constant,
+// once 4096 keys are tracked new keys share a single one.
+func (l Logger) Once(key string) Logger {
+	key = "once|" + key
+	return l.limit(func(zapcore.Entry) string { return key }, 0)
+}
+
+func (l Logger) limit(key func(zapcore.Entry) string, interval time.Duration) Logger {
+	limiter := &l.root.limiter
+	l.sugared = l.sugared.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &limitCore{Core: core, limiter: limiter, key: key, interval: interval}
+	})).Sugar()
+	return l
+}
+
+// Every returns a rate limited logger derived from the standard logger. See
+// Logger.Every.
+func Every(interval time.Duration) Logger {
+	return std.Every(interval)
+}
+
+// Once returns a logger derived from the standard logger that writes only
+// the first entry logged with key. See Logger.Once.
+func Once(key string) Logger {
+	return std.Once(key)
+}
+
+// rateLimiter 记录每个 key 上一次输出的时间和之后被抑制的次数
+type rateLimiter struct {
+	mut      sync.Mutex
+	entries  map[string]*limitEntry
+	overflow *limitEntry // 记录数达到上限之后新的 key 共用的记录
+	swept    time.Time
+	now      func() time.Time
+}
+
+type limitEntry struct {
+	last       time.Time
+	interval   time.Duration
+	suppressed uint64
+}
+
+// allow 判断 key 对应的日志是否可以输出，可以输出时返回之前被抑制的次数；interval 为 0 时只允许第一次
+func (r *rateLimiter) allow(key string, interval time.Duration) (bool, uint64) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+	if r.entries == nil {
+		r.entries = make(map[string]*limitEntry)
+	}
+
+	e, ok := r.entries[key]
+	if !ok {
+		if len(r.entries) >= maxRateLimitEntries && now.Sub(r.swept) >= rateLimitSweepInterval {
+			r.sweep(now)
+			r.swept = now
+		}
+		if len(r.entries) < maxRateLimitEntries {
+			r.entries[key] = &limitEntry{last: now, interval: interval}
+			return true, 0
+		}
+		// key 中带有变量时记录会无限增长，超过上限的 key 一起限流
+		if r.overflow == nil {
+			r.overflow = &limitEntry{last: now, interval: interval}
+			return true, 0
+		}
+		e = r.overflow
+	}
+
+	if interval <= 0 || now.Sub(e.last) < interval {
+		e.suppressed++
+		return false, 0
+	}
+	n := e.suppressed
+	e.last = now
+	e.suppressed = 0
+	return true, n
+}
+
+// sweep 删除已经过了限流周期的记录，Once 的记录不会被删除
+func (r *rateLimiter) sweep(now time.Time) {
+	for key, e := range r.entries {
+		if e.interval > 0 && now.Sub(e.last) >= e.interval {
+			delete(r.entries, key)
+		}
+	}
+}
+
+// limitCore 按 key 对日志限流，在恢复输出的日志中附带被抑制的数量
+type limitCore struct {
+	zapcore.Core
+	limiter  *rateLimiter
+	key      func(zapcore.Entry) string
+	interval time.Duration
+}
+
+func (c *limitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &limitCore{
+		Core:     c.Core.With(fields),
+		limiter:  c.limiter,
+		key:      c.key,
+		interval: c.interval,
+	}
+}
+
+func (c *limitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// panic 和 fatal 级别的日志不限流
+	if ent.Level >= zapcore.DPanicLevel {
+		return c.Core.Check(ent, ce)
+	}
+	// 先由内部的 core 判断是否输出，被 Levels 等过滤掉的日志不占用限流的名额
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+
+	ok, suppressed := c.limiter.allow(c.key(ent), c.interval)
+	if !ok {
+		return ce
+	}
+	w := &limitedCore{inner: inner}
+	if suppressed > 0 {
+		w.fields = []zapcore.Field{zap.Uint64(SuppressedKey, suppressed)}
+	}
+	return ce.AddCore(ent, w)
+}
+
+// limitedCore 保存通过限流的 Check 结果，Write 时附带被抑制的数量
+type limitedCore struct {
+	inner  *zapcore.CheckedEntry
+	fields []zapcore.Field
+}
+
+func (c *limitedCore) Enabled(zapcore.Level) bool        { return true }
+func (c *limitedCore) With([]zapcore.Field) zapcore.Core { return c }
+func (c *limitedCore) Sync() error                       { return nil }
+func (c *limitedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *limitedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if len(c.fields) > 0 {
+		fields = append(c.fields, fields...)
+	}
+	// Entry 中的 caller 和 stack 在 Check 之后才填充
+	c.inner.Entry = ent
+	c.inner.ErrorOutput = zapcore.Lock(os.Stderr)
+	c.inner.Write(fields...)
+	return nil
+}
@@ -0,0 +1,134 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerEvery(t *testing.T) {
+	l, buf := newBufferLogger(DebugLevel)
+	clock := &fakeClock{t: time.Date(2021, 8, 1, 10, 0, 0, 0, time.Local)}
+	l.root.limiter.now = clock.now
+
+	for i := 0; i < 5; i++ {
+		l.Every(time.Minute).Warnf("bad file %s", "a.json")
+		l.Every(time.Minute).With("k", "v").Warnf("bad file %s", "b.json")
+	}
+	clock.add(time.Minute)
+	l.Every(time.Minute).Warnf("bad file %s", "a.json")
+	l.Every(time.Minute).Infof("bad file %s", "a.json")
+
+	assert.Equal(t, "level=warn msg=\"bad file a.json\"\n"+
+		"level=warn msg=\"bad file b.json\" k=v\n"+
+		"level=warn msg=\"bad file a.json\" suppressed=4\n"+
+		"level=info msg=\"bad file a.json\"\n", buf.String())
+}
+
+func TestLoggerOnce(t *testing.T) {
+	l, buf := newBufferLogger(InfoLevel)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Once("deprecated").Warn("option is deprecated")
+				l.Once("disabled").Debug("debug is not counted")
+			}
+		}()
+	}
+	wg.Wait()
+	l.Once("disabled").Error("logged once")
+
+	assert.Equal(t, 1, strings.Count(buf.String(), "option is deprecated"))
+	assert.Equal(t, 1, strings.Count(buf.String(), "logged once"))
+	assert.NotContains(t, buf.String(), "debug is not counted")
+}
+
+func TestLoggerEveryLevels(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	opt := Options{Filename: filename, Levels: map[string]string{"host": "error"}}
+	l := New(opt)
+	defer l.Close()
+
+	// 被 Levels 过滤的日志不占用限流的名额，也不计入被抑制的数量
+	host := l.Named("host")
+	for i := 0; i < 3; i++ {
+		host.Every(time.Hour).Warn("disk is full")
+		host.Once("deprecated").Warn("option is deprecated")
+	}
+	assert.Empty(t, readLines(t, filename))
+
+	opt.Levels = nil
+	assert.NoError(t, l.Reload(opt))
+	host.Every(time.Hour).Warn("disk is full")
+	host.Once("deprecated").Warn("option is deprecated")
+
+	lines := readLines(t, filename)
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[0], `msg="disk is full"`)
+		assert.NotContains(t, lines[0], SuppressedKey)
+		assert.Contains(t, lines[1], `msg="option is deprecated"`)
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2021, 8, 1, 10, 0, 0, 0, time.Local)}
+	r := &rateLimiter{now: clock.now}
+	r.allow("once", 0)
+	for i := 0; i < maxRateLimitEntries; i++ {
+		r.allow(strings.Repeat("k", i), time.Second)
+	}
+	clock.add(time.Second)
+	r.allow("new", time.Second)
+	assert.Len(t, r.entries, 2)
+
+	ok, _ := r.allow("once", 0)
+	assert.False(t, ok)
+}
+
+func TestRateLimiterMaxEntries(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2021, 8, 1, 10, 0, 0, 0, time.Local)}
+	r := &rateLimiter{now: clock.now}
+	for i := 0; i < maxRateLimitEntries; i++ {
+		ok, _ := r.allow(strconv.Itoa(i), time.Minute)
+		assert.True(t, ok)
+	}
+
+	// 达到上限之后新的 key 共用同一条记录，记录数不再增长
+	allowed := 0
+	for i := 0; i < 20000; i++ {
+		if ok, _ := r.allow("extra-"+strconv.Itoa(i), time.Minute); ok {
+			allowed++
+		}
+	}
+	assert.Equal(t, 1, allowed)
+	assert.Len(t, r.entries, maxRateLimitEntries)
+
+	// 过期之后清理出空间，新的 key 重新单独记录
+	clock.add(time.Minute)
+	ok, suppressed := r.allow("fresh", time.Minute)
+	assert.True(t, ok)
+	assert.Zero(t, suppressed)
+	assert.Len(t, r.entries, 1)
+	ok, suppressed = r.allow("extra-1", time.Minute)
+	assert.True(t, ok)
+	assert.Zero(t, suppressed)
+}
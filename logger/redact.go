@@ -0,0 +1,381 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	RedactMask = "mask"
+	RedactHash = "hash"
+
+	defaultRedactMask = "******"
+	redactHashPrefix  = "hmac:"
+)
+
+// RedactOptions is the option set for hiding sensitive values. A field is
+// redacted as a whole when its key matches Keys or KeyPatterns, including
+// keys inside nested objects and maps. Parts of string values and messages
+// matching ValuePatterns are redacted in place.
+type RedactOptions struct {
+	// Keys are the field names to redact, compared case-insensitively,
+	// e.g. "access_token" and "password".
+	Keys []string `yaml:"keys"`
+
+	// KeyPatterns are regular expressions matched against field names,
+	// e.g. "(?i)secret".
+	KeyPatterns []string `yaml:"key_patterns"`
+
+	// ValuePatterns are regular expressions matched against string values
+	// and messages, e.g. `Bearer\s+\S+` or `1[3-9]\d{9}` for phone numbers.
+	ValuePatterns []string `yaml:"value_patterns"`
+
+	// Mode is how a value is redacted, Valid values are "mask" and "hash",
+	// default is mask. hash replaces the value with the first 16 hex digits
+	// of its HMAC-SHA256 under HashKey so that equal values can still be
+	// correlated, it requires HashKey.
+	Mode string `yaml:"mode"`
+
+	// HashKey is the secret key of the hash mode. Without the key a hashed
+	// value can't be brute-forced even when it has little entropy such as a
+	// phone number, so keep it out of the code and the logs, e.g. set it by
+	// ApplyEnv. Values can only be correlated between loggers using the same key.
+	HashKey string `yaml:"hash_key"`
+
+	// Mask is the replacement in mask mode, default is "******".
+	Mask string `yaml:"mask"`
+}
+
+// redactor 根据配置对字段进行脱敏，所有 sink 共享
+type redactor struct {
+	keys          map[string]struct{}
+	keyPatterns   []*regexp.Regexp
+	valuePatterns []*regexp.Regexp
+	hash          bool
+	hashKey       []byte
+	mask          string
+}
+
+func newRedactor(opt RedactOptions) (*redactor, error) {
+	r := &redactor{
+		keys: make(map[string]struct{}, len(opt.Keys)),
+		mask: opt.Mask,
+	}
+	switch opt.Mode {
+	case "", RedactMask:
+	case RedactHash:
+		if opt.HashKey == "" {
+			return nil, fmt.Errorf("redact mode %q requires hash_key", RedactHash)
+		}
+		r.hash = true
+		r.hashKey = []byte(opt.HashKey)
+	default:
+		return nil, fmt.Errorf("unknown redact mode: %q", opt.Mode)
+	}
+	if r.mask == "" {
+		r.mask = defaultRedactMask
+	}
+
+	for _, key := range opt.Keys {
+		r.keys[strings.ToLower(key)] = struct{}{}
+	}
+	for _, p := range opt.KeyPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact key pattern: %w", err)
+		}
+		r.keyPatterns = append(r.keyPatterns, re)
+	}
+	for _, p := range opt.ValuePatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact value pattern: %w", err)
+		}
+		r.valuePatterns = append(r.valuePatterns, re)
+	}
+	return r, nil
+}
+
+func (r *redactor) matchKey(key string) bool {
+	if _, ok := r.keys[strings.ToLower(key)]; ok {
+		return true
+	}
+	for _, re := range r.keyPatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// replace 返回 v 脱敏后的值
+func (r *redactor) replace(v string) string {
+	if !r.hash {
+		return r.mask
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(v))
+	return redactHashPrefix + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// redactString 替换 s 中所有匹配 ValuePatterns 的部分
+func (r *redactor) redactString(s string) string {
+	for _, re := range r.valuePatterns {
+		s = re.ReplaceAllStringFunc(s, r.replace)
+	}
+	return s
+}
+
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	if len(fields) == 0 {
+		return fields
+	}
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = r.field(f)
+	}
+	return redacted
+}
+
+// field 返回脱敏后的字段，嵌套的对象和数组在编码时再处理
+func (r *redactor) field(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.NamespaceType, zapcore.SkipType:
+		return f
+	case zapcore.InlineMarshalerType:
+		f.Interface = redactObject{ObjectMarshaler: f.Interface.(zapcore.ObjectMarshaler), r: r}
+		return f
+	}
+
+	if r.matchKey(f.Key) {
+		return zap.String(f.Key, r.replace(renderField(f)))
+	}
+
+	switch f.Type {
+	case zapcore.ObjectMarshalerType:
+		f.Interface = redactObject{ObjectMarshaler: f.Interface.(zapcore.ObjectMarshaler), r: r}
+	case zapcore.ArrayMarshalerType:
+		f.Interface = redactArray{ArrayMarshaler: f.Interface.(zapcore.ArrayMarshaler), r: r}
+	case zapcore.ReflectType:
+		f.Interface = r.reflected(f.Interface)
+	case zapcore.StringType:
+		f.String = r.redactString(f.String)
+	case zapcore.ByteStringType:
+		s := string(f.Interface.([]byte))
+		if redacted := r.redactString(s); redacted != s {
+			return zap.String(f.Key, redacted)
+		}
+	case zapcore.StringerType, zapcore.ErrorType:
+		if len(r.valuePatterns) == 0 {
+			return f
+		}
+		s := renderField(f)
+		if redacted := r.redactString(s); redacted != s {
+			return zap.String(f.Key, redacted)
+		}
+	}
+	return f
+}
+
+// reflected 通过 json 转换为通用的 map 和 slice 后脱敏，与 encoder 输出反射值的方式一致
+func (r *redactor) reflected(v interface{}) interface{} {
+	if v == nil {
+		return v
+	}
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+	case reflect.String:
+		s := fmt.Sprint(v)
+		if redacted := r.redactString(s); redacted != s {
+			return redacted
+		}
+		return v
+	default:
+		return v
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return v
+	}
+	return r.generic(generic)
+}
+
+func (r *redactor) generic(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if r.matchKey(k) {
+				val[k] = r.replace(fmt.Sprint(item))
+			} else {
+				val[k] = r.generic(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = r.generic(item)
+		}
+	case string:
+		return r.redactString(val)
+	}
+	return v
+}
+
+// renderField 将字段的值转换为字符串，用于计算 hash
+func renderField(f zapcore.Field) (s string) {
+	if f.Type == zapcore.StringType {
+		return f.String
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			s = fmt.Sprintf("%v", err)
+		}
+	}()
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return fmt.Sprint(enc.Fields[f.Key])
+}
+
+// redactCore 在写入 sink 之前对日志内容脱敏
+type redactCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.fields(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.redactString(ent.Message)
+	return c.Core.Write(ent, c.r.fields(fields))
+}
+
+type redactObject struct {
+	zapcore.ObjectMarshaler
+	r *redactor
+}
+
+func (o redactObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.ObjectMarshaler.MarshalLogObject(&redactObjectEncoder{ObjectEncoder: enc, r: o.r})
+}
+
+type redactArray struct {
+	zapcore.ArrayMarshaler
+	r *redactor
+}
+
+func (a redactArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.ArrayMarshaler.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: a.r})
+}
+
+// redactObjectEncoder 将嵌套对象中的每个字段交给 redactor 处理后再写入原 encoder
+type redactObjectEncoder struct {
+	zapcore.ObjectEncoder
+	r *redactor
+}
+
+func (e *redactObjectEncoder) add(f zapcore.Field) {
+	e.r.field(f).AddTo(e.ObjectEncoder)
+}
+
+func (e *redactObjectEncoder) AddArray(k string, v zapcore.ArrayMarshaler) error {
+	e.add(zap.Array(k, v))
+	return nil
+}
+
+func (e *redactObjectEncoder) AddObject(k string, v zapcore.ObjectMarshaler) error {
+	e.add(zap.Object(k, v))
+	return nil
+}
+
+func (e *redactObjectEncoder) AddReflected(k string, v interface{}) error {
+	e.add(zap.Reflect(k, v))
+	return nil
+}
+
+func (e *redactObjectEncoder) AddBinary(k string, v []byte)          { e.add(zap.Binary(k, v)) }
+func (e *redactObjectEncoder) AddByteString(k string, v []byte)      { e.add(zap.ByteString(k, v)) }
+func (e *redactObjectEncoder) AddBool(k string, v bool)              { e.add(zap.Bool(k, v)) }
+func (e *redactObjectEncoder) AddComplex128(k string, v complex128)  { e.add(zap.Complex128(k, v)) }
+func (e *redactObjectEncoder) AddComplex64(k string, v complex64)    { e.add(zap.Complex64(k, v)) }
+func (e *redactObjectEncoder) AddDuration(k string, v time.Duration) { e.add(zap.Duration(k, v)) }
+func (e *redactObjectEncoder) AddFloat64(k string, v float64)        { e.add(zap.Float64(k, v)) }
+func (e *redactObjectEncoder) AddFloat32(k string, v float32)        { e.add(zap.Float32(k, v)) }
+func (e *redactObjectEncoder) AddInt(k string, v int)                { e.add(zap.Int(k, v)) }
+func (e *redactObjectEncoder) AddInt64(k string, v int64)            { e.add(zap.Int64(k, v)) }
+func (e *redactObjectEncoder) AddInt32(k string, v int32)            { e.add(zap.Int32(k, v)) }
+func (e *redactObjectEncoder) AddInt16(k string, v int16)            { e.add(zap.Int16(k, v)) }
+func (e *redactObjectEncoder) AddInt8(k string, v int8)              { e.add(zap.Int8(k, v)) }
+func (e *redactObjectEncoder) AddString(k, v string)                 { e.add(zap.String(k, v)) }
+func (e *redactObjectEncoder) AddTime(k string, v time.Time)         { e.add(zap.Time(k, v)) }
+func (e *redactObjectEncoder) AddUint(k string, v uint)              { e.add(zap.Uint(k, v)) }
+func (e *redactObjectEncoder) AddUint64(k string, v uint64)          { e.add(zap.Uint64(k, v)) }
+func (e *redactObjectEncoder) AddUint32(k string, v uint32)          { e.add(zap.Uint32(k, v)) }
+func (e *redactObjectEncoder) AddUint16(k string, v uint16)          { e.add(zap.Uint16(k, v)) }
+func (e *redactObjectEncoder) AddUint8(k string, v uint8)            { e.add(zap.Uint8(k, v)) }
+func (e *redactObjectEncoder) AddUintptr(k string, v uintptr)        { e.add(zap.Uintptr(k, v)) }
+
+// redactArrayEncoder 数组元素没有 key，只处理字符串和嵌套的对象
+type redactArrayEncoder struct {
+	zapcore.ArrayEncoder
+	r *redactor
+}
+
+func (e *redactArrayEncoder) AppendArray(v zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(redactArray{ArrayMarshaler: v, r: e.r})
+}
+
+func (e *redactArrayEncoder) AppendObject(v zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(redactObject{ObjectMarshaler: v, r: e.r})
+}
+
+func (e *redactArrayEncoder) AppendReflected(v interface{}) error {
+	return e.ArrayEncoder.AppendReflected(e.r.reflected(v))
+}
+
+func (e *redactArrayEncoder) AppendString(v string) {
+	e.ArrayEncoder.AppendString(e.r.redactString(v))
+}
+
+func (e *redactArrayEncoder) AppendByteString(v []byte) {
+	if len(e.r.valuePatterns) == 0 {
+		e.ArrayEncoder.AppendByteString(v)
+		return
+	}
+	e.ArrayEncoder.AppendString(e.r.redactString(string(v)))
+}
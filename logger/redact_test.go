@@ -0,0 +1,96 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type credential struct {
+	User     string
+	Password string
+}
+
+func (c credential) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", c.User)
+	enc.AddString("password", c.Password)
+	return nil
+}
+
+func TestLoggerRedact(t *testing.T) {
+	dir := t.TempDir()
+	sinks := map[string]string{
+		"json":    filepath.Join(dir, "json.log"),
+		"console": filepath.Join(dir, "console.log"),
+		"logfmt":  filepath.Join(dir, "logfmt.log"),
+	}
+	opt := Options{
+		Redact: &RedactOptions{
+			Keys:          []string{"access_token", "Password"},
+			KeyPatterns:   []string{`(?i)secret`},
+			ValuePatterns: []string{`Bearer\s+\S+`, `1[3-9]\d{9}`},
+		},
+	}
+	for format, filename := range sinks {
+		opt.Sinks = append(opt.Sinks, SinkOptions{Type: SinkFile, Filename: filename, Format: format})
+	}
+	l := New(opt)
+
+	l.With("access_token", "token-in-with").Infow("report from 13812345678",
+		"PASSWORD", "hunter2",
+		"app_secret", 123456,
+		"header", "Authorization: Bearer abc.def",
+		"payload", map[string]interface{}{"access_token": "token-in-map", "data": []string{"13900000000"}},
+		"err", errors.New("bad token Bearer xyz"),
+	)
+	l.Infow("object", zap.Object("cred", credential{User: "admin", Password: "nested-password"}))
+	assert.NoError(t, l.Close())
+
+	for format, filename := range sinks {
+		lines := readLines(t, filename)
+		if !assert.Len(t, lines, 2, format) {
+			continue
+		}
+		for _, secret := range []string{"token-in-with", "13812345678", "hunter2", "123456", "abc.def", "token-in-map", "13900000000", "xyz", "nested-password"} {
+			assert.NotContains(t, lines[0]+lines[1], secret, format)
+		}
+		assert.Contains(t, lines[1], "admin", format)
+		assert.Contains(t, lines[0], "******", format)
+	}
+}
+
+func TestRedactorHash(t *testing.T) {
+	r, err := newRedactor(RedactOptions{Keys: []string{"token"}, Mode: RedactHash, HashKey: "secret"})
+	assert.NoError(t, err)
+
+	f := r.field(zap.String("token", "abc"))
+	assert.Equal(t, "hmac:9946dad4e00e913f", f.String)
+	assert.Equal(t, f, r.field(zap.String("token", "abc")))
+	assert.Equal(t, zap.String("user", "abc"), r.field(zap.String("user", "abc")))
+
+	// 不同的 key 得到不同的结果，没有 key 时拒绝使用 hash
+	r, err = newRedactor(RedactOptions{Keys: []string{"token"}, Mode: RedactHash, HashKey: "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, "hmac:c05a688e1ec55995", r.field(zap.String("token", "abc")).String)
+	_, err = newRedactor(RedactOptions{Mode: RedactHash})
+	assert.EqualError(t, err, `redact mode "hash" requires hash_key`)
+	assert.Error(t, Options{Redact: &RedactOptions{Mode: RedactHash}}.Validate())
+
+	_, err = newRedactor(RedactOptions{Mode: "drop"})
+	assert.EqualError(t, err, `unknown redact mode: "drop"`)
+	assert.Error(t, Options{Redact: &RedactOptions{ValuePatterns: []string{"("}}}.Validate())
+}
@@ -0,0 +1,329 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultWatchInterval = 5 * time.Second
+
+// generation 是一次 Build 的结果，热加载时整体替换
+type generation struct {
+	core  zapcore.Core
+	hooks *hookCore
+	level zap.AtomicLevel
+	res   *resources
+	state *loggerState
+
+	// 写入时持有读锁，替换后持有写锁等待正在进行的写入完成
+	mut     sync.RWMutex
+	retired bool
+}
+
+// loggerState 保存运行时注册的 hook 和统计数据，热加载时沿用
+type loggerState struct {
+	hooks   hookRegistry
+	metrics metrics
+}
+
+// loggerRoot 由同一个 logger 派生出的所有 logger 共享，保存当前生效的 generation
+type loggerRoot struct {
+	gen atomic.Value // *generation
+
+	// 串行化 reload
+	mut sync.Mutex
+
+	// Every 和 Once 的状态，热加载后保留
+	limiter rateLimiter
+}
+
+func (r *loggerRoot) load() *generation {
+	return r.gen.Load().(*generation)
+}
+
+// swap 替换当前的 generation，等待旧配置上的写入完成后排空并关闭旧的输出。
+// 新旧配置通常写入同一个文件，旧的输出关闭之前新配置上的写入会被阻塞，避免两者同时写入、切割同一个文件
+func (r *loggerRoot) swap(gen *generation) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	gen.mut.Lock()
+	defer gen.mut.Unlock()
+
+	old := r.load()
+	r.gen.Store(gen)
+
+	old.mut.Lock()
+	old.retired = true
+	old.mut.Unlock()
+
+	return multierr.Append(old.core.Sync(), old.res.close())
+}
+
+// newLogger 构造以 gen 为初始配置的 logger
+func newLogger(gen *generation, opts ...zap.Option) Logger {
+	root := new(loggerRoot)
+	root.gen.Store(gen)
+	return Logger{
+		sugared: zap.New(&swapCore{root: root}, opts...).Sugar(),
+		root:    root,
+	}
+}
+
+// swapCore 总是使用 root 中当前的 generation，With 添加的字段在 generation 变化时重新绑定
+type swapCore struct {
+	root   *loggerRoot
+	fields []zapcore.Field
+	bound  atomic.Value // *boundCore
+}
+
+type boundCore struct {
+	gen   *generation
+	core  zapcore.Core
+	hooks *hookCore
+}
+
+func (c *swapCore) bind() *boundCore {
+	gen := c.root.load()
+	if b, ok := c.bound.Load().(*boundCore); ok && b.gen == gen {
+		return b
+	}
+
+	b := &boundCore{gen: gen, core: gen.core, hooks: gen.hooks}
+	if len(c.fields) > 0 {
+		b.core = b.core.With(c.fields)
+		if b.hooks != nil {
+			b.hooks = b.hooks.With(c.fields)
+		}
+	}
+	c.bound.Store(b)
+	return b
+}
+
+func (c *swapCore) current() (*generation, zapcore.Core) {
+	b := c.bind()
+	return b.gen, b.core
+}
+
+func (c *swapCore) Enabled(lvl zapcore.Level) bool {
+	_, core := c.current()
+	return core.Enabled(lvl)
+}
+
+func (c *swapCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &swapCore{root: c.root, fields: merged}
+}
+
+// Check 依次追加输出、hook 以及 Fatal 时关闭输出的 core。只有输出在 generation 的读锁内执行，
+// hook 中再次输出日志时不会与正在等待旧输出排空的 Reload 互相等待
+func (c *swapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	b := c.bind()
+	ce = c.check(b, ent, ce)
+	if b.hooks != nil {
+		ce = b.hooks.Check(ent, ce)
+	}
+	if ent.Level == zapcore.FatalLevel {
+		ce = ce.AddCore(ent, closeCore{res: b.gen.res})
+	}
+	return ce
+}
+
+func (c *swapCore) check(b *boundCore, ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	inner := b.core.Check(ent, nil)
+	if inner == nil {
+		return ce
+	}
+	return ce.AddCore(ent, &checkedCore{swap: c, gen: b.gen, inner: inner})
+}
+
+func (c *swapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	for {
+		gen, core := c.current()
+		gen.mut.RLock()
+		if gen.retired {
+			gen.mut.RUnlock()
+			continue
+		}
+		err := core.Write(ent, fields)
+		gen.mut.RUnlock()
+		return err
+	}
+}
+
+func (c *swapCore) Sync() error {
+	_, core := c.current()
+	return core.Sync()
+}
+
+// checkedCore 保存 Check 时得到的结果，Write 时如果配置已被替换则按新配置重新处理
+type checkedCore struct {
+	swap  *swapCore
+	gen   *generation
+	inner *zapcore.CheckedEntry
+}
+
+func (c *checkedCore) Enabled(zapcore.Level) bool        { return true }
+func (c *checkedCore) With([]zapcore.Field) zapcore.Core { return c }
+func (c *checkedCore) Sync() error                       { return nil }
+func (c *checkedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *checkedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.gen.mut.RLock()
+	if c.gen.retired {
+		c.gen.mut.RUnlock()
+		// 旧的输出已经关闭，按新的配置重新输出，hook 已经在 Check 时追加，不再重复调用
+		if ce := c.swap.check(c.swap.bind(), ent, nil); ce != nil {
+			ce.ErrorOutput = zapcore.Lock(os.Stderr)
+			ce.Write(fields...)
+		}
+		return nil
+	}
+	defer c.gen.mut.RUnlock()
+
+	// Entry 中的 caller 和 stack 在 Check 之后才填充
+	c.inner.Entry = ent
+	c.inner.ErrorOutput = zapcore.Lock(os.Stderr)
+	c.inner.Write(fields...)
+	return nil
+}
+
+// Reload rebuilds the sinks from opt and switches the logger and every
+// logger derived from it to the new configuration. Entries being written
+// are finished on the old sinks, which are then drained and closed. Entries
+// logged in the meantime wait until the old sinks are closed, so the old and
+// new sinks never write the same file at the same time. The current
+// configuration is kept if opt is invalid.
+func (l Logger) Reload(opt Options) error {
+	gen, err := buildGeneration(opt, l.root.load().state)
+	if err != nil {
+		return err
+	}
+	return l.root.swap(gen)
+}
+
+// ReloadOnSignal reloads the logger with the options returned by load
+// whenever one of sigs is received, SIGHUP by default. Failures are logged
+// and the current configuration is kept. Call stop to stop listening.
+func (l Logger) ReloadOnSignal(load func() (Options, error), sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-ch:
+				l.reloadFrom(load, "signal", sig.String())
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// WatchConfig polls the config file at path every interval, 5s by default,
+// and reloads the logger when its size or modification time changes. load
+// defaults to LoadOptions(path). Call stop to stop watching.
+func (l Logger) WatchConfig(path string, interval time.Duration, load func() (Options, error)) (stop func()) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	if load == nil {
+		load = func() (Options, error) { return LoadOptions(path) }
+	}
+
+	last, _ := os.Stat(path)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				// 文件可能正在被替换，等待下一个周期
+				continue
+			}
+			if last != nil && info.Size() == last.Size() && info.ModTime().Equal(last.ModTime()) {
+				continue
+			}
+			last = info
+			l.reloadFrom(load, "file", path)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+func (l Logger) reloadFrom(load func() (Options, error), trigger, source string) {
+	opt, err := load()
+	if err == nil {
+		err = l.Reload(opt)
+	}
+	if err != nil {
+		l.sugared.Errorw("failed to reload logger options", trigger, source, "error", err)
+		return
+	}
+	l.sugared.Infow("logger options reloaded", trigger, source)
+}
+
+// Reload switches the standard logger and every logger derived from it to
+// the new configuration. See Logger.Reload.
+func Reload(opt Options) error {
+	return std.Reload(opt)
+}
+
+// ReloadOnSignal reloads the standard logger on signals. See
+// Logger.ReloadOnSignal.
+func ReloadOnSignal(load func() (Options, error), sigs ...os.Signal) (stop func()) {
+	return std.ReloadOnSignal(load, sigs...)
+}
+
+// WatchConfig reloads the standard logger when the config file changes. See
+// Logger.WatchConfig.
+func WatchConfig(path string, interval time.Duration, load func() (Options, error)) (stop func()) {
+	return std.WatchConfig(path, interval, load)
+}
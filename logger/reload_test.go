@@ -0,0 +1,221 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func readLines(t *testing.T, filename string) []string {
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func TestLoggerReloadDerived(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.log")
+	second := filepath.Join(dir, "second.log")
+
+	l := New(Options{Level: "info", Filename: first})
+	derived := l.With("component", "host").Named("watcher")
+	derived.Info("before reload")
+
+	assert.NoError(t, l.Reload(Options{Level: "debug", Format: "json", Filename: second}))
+	derived.Debug("after reload")
+	assert.Equal(t, DebugLevel, derived.GetLevel())
+	assert.NoError(t, l.Close())
+
+	lines := readLines(t, first)
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `logger=watcher`)
+	assert.Contains(t, lines[0], `msg="before reload" component=host`)
+
+	lines = readLines(t, second)
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"logger":"watcher"`)
+	assert.Contains(t, lines[0], `"msg":"after reload"`)
+	assert.Contains(t, lines[0], `"component":"host"`)
+}
+
+func TestLoggerReloadInvalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	l := New(Options{Filename: filename})
+	defer l.Close()
+
+	assert.Error(t, l.Reload(Options{Level: "verbose"}))
+	l.Info("still working")
+	assert.Len(t, readLines(t, filename), 1)
+}
+
+func TestLoggerReloadConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	opt := func(i int) Options {
+		return Options{
+			Filename: filepath.Join(dir, fmt.Sprintf("app-%d.log", i)),
+			Async:    &AsyncOptions{QueueSize: 16},
+		}
+	}
+
+	l := New(opt(0))
+	const workers, entries, reloads = 4, 500, 10
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := l.With("worker", i)
+			for j := 0; j < entries; j++ {
+				w.Infow("entry", "seq", j)
+			}
+		}(i)
+	}
+	for i := 1; i <= reloads; i++ {
+		assert.NoError(t, l.Reload(opt(i)))
+	}
+	wg.Wait()
+	assert.NoError(t, l.Close())
+
+	total := 0
+	for i := 0; i <= reloads; i++ {
+		total += len(readLines(t, opt(i).Filename))
+	}
+	assert.Equal(t, workers*entries, total)
+}
+
+func TestLoggerReloadSameFileAsync(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	opt := Options{Filename: filename, Async: &AsyncOptions{}}
+
+	l := New(opt)
+	const workers, entries, reloads = 8, 2000, 30
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := l.With("worker", i)
+			for j := 0; j < entries; j++ {
+				w.Infow("entry", "seq", j)
+			}
+		}(i)
+	}
+	for i := 0; i < reloads; i++ {
+		assert.NoError(t, l.Reload(opt))
+	}
+	wg.Wait()
+	assert.NoError(t, l.Close())
+
+	// 新旧配置写入同一个文件时既不丢失也不会出现被截断的行
+	lines := readLines(t, filename)
+	assert.Len(t, lines, workers*entries)
+	for _, line := range lines {
+		if !assert.Contains(t, line, "msg=entry") {
+			break
+		}
+	}
+}
+
+func TestLoggerReloadHookLogs(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	opt := Options{Filename: filename}
+	l := New(opt)
+
+	// hook 中通过同一个 logger 输出告警，并且在热加载开始等待旧输出排空之后才输出
+	inHook := make(chan struct{})
+	reloading := make(chan struct{})
+	l.AddHook(Hook{Level: ErrorLevel, Fire: func(zapcore.Entry, []zapcore.Field) {
+		close(inHook)
+		<-reloading
+		time.Sleep(50 * time.Millisecond)
+		l.Warn("alert")
+	}})
+
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		l.Error("failed")
+	}()
+	<-inHook
+
+	reloaded := make(chan error, 1)
+	go func() {
+		close(reloading)
+		reloaded <- l.Reload(opt)
+	}()
+
+	select {
+	case <-logged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hook logging during Reload deadlocked")
+	}
+	select {
+	case err := <-reloaded:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload deadlocked with a hook logging through the same logger")
+	}
+	assert.NoError(t, l.Close())
+
+	lines := readLines(t, filename)
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[0], "msg=failed")
+		assert.Contains(t, lines[1], "msg=alert")
+	}
+}
+
+func TestLoggerWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "logger.yaml")
+	first := filepath.Join(dir, "first.log")
+	second := filepath.Join(dir, "second.log")
+
+	assert.NoError(t, ioutil.WriteFile(config, []byte("filename: "+first+"\n"), 0644))
+	opt, err := LoadOptions(config)
+	assert.NoError(t, err)
+	l := New(opt)
+	defer l.Close()
+
+	stop := l.WatchConfig(config, 10*time.Millisecond, nil)
+	defer stop()
+
+	assert.NoError(t, ioutil.WriteFile(config, []byte("level: debug\nfilename: "+second+"\n"), 0644))
+	assert.Eventually(t, func() bool { return l.GetLevel() == DebugLevel }, time.Second, 10*time.Millisecond)
+	l.Warn("after watch")
+
+	lines := readLines(t, second)
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+	assert.Contains(t, lines[0], `msg="logger options reloaded" file=`+config)
+	assert.Contains(t, lines[1], `msg="after watch"`)
+}
@@ -0,0 +1,175 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+// reopener 由文件输出实现，关闭当前文件并重新打开同名文件
+type reopener interface {
+	Reopen() error
+}
+
+// Reopen closes and reopens the log files of all file sinks, so that entries
+// go to the new file after an external tool such as logrotate has renamed
+// the old one.
+func (l Logger) Reopen() error {
+	var errs error
+	for _, r := range l.root.load().res.reopeners {
+		errs = multierr.Append(errs, r.Reopen())
+	}
+	return errs
+}
+
+// ReopenOnSignal reopens the log files whenever one of sigs is received,
+// SIGUSR1 by default. Failures are logged. Call stop to stop listening.
+func (l Logger) ReopenOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = defaultReopenSignals
+	}
+	if len(sigs) == 0 {
+		// 当前平台没有默认的信号
+		return func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-ch:
+				if err := l.Reopen(); err != nil {
+					l.sugared.Errorw("failed to reopen log files", "signal", sig.String(), "error", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// Reopen closes and reopens the log files of the standard logger.
+func Reopen() error {
+	return std.Reopen()
+}
+
+// ReopenOnSignal reopens the log files of the standard logger on signals.
+// See Logger.ReopenOnSignal.
+func ReopenOnSignal(sigs ...os.Signal) (stop func()) {
+	return std.ReopenOnSignal(sigs...)
+}
+
+// Reopen 关闭当前文件，lumberjack 会在下一次写入时重新打开同名文件
+func (s lumberjackSyncer) Reopen() error {
+	return s.Logger.Close()
+}
+
+// Reopen 关闭当前文件并立即重新打开，周期以新文件的修改时间为准
+func (w *rotateWriter) Reopen() error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if err := w.close(); err != nil {
+		return err
+	}
+	return w.openExisting()
+}
+
+// fileWriter 只追加写入文件不做切割，用于配合 logrotate 等外部工具
+type fileWriter struct {
+	filename string
+
+	mut  sync.Mutex
+	file *os.File
+}
+
+func newFileWriter(filename string) *fileWriter {
+	return &fileWriter{filename: filename}
+}
+
+// Write implements io.Writer
+func (w *fileWriter) Write(p []byte) (int, error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	return w.file.Write(p)
+}
+
+// Sync implements zapcore.WriteSyncer
+func (w *fileWriter) Sync() error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close implements io.Closer
+func (w *fileWriter) Close() error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	return w.close()
+}
+
+// Reopen 关闭当前文件并立即重新打开，打开失败时在下一次写入时重试
+func (w *fileWriter) Reopen() error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if err := w.close(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+func (w *fileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	return nil
+}
+
+func (w *fileWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,54 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerReopen(t *testing.T) {
+	for name, opt := range map[string]SinkOptions{
+		"external":   {DisableRotation: true},
+		"lumberjack": {MaxSize: 100},
+		"rotate":     {Rotation: RotationDaily},
+	} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "app.log")
+			opt.Type = SinkFile
+			opt.Filename = filename
+			l := New(Options{Sinks: []SinkOptions{opt}})
+			defer l.Close()
+
+			l.Info("first")
+			// 模拟 logrotate 的 create 模式
+			assert.NoError(t, os.Rename(filename, filename+".1"))
+			l.Info("second")
+			assert.NoError(t, l.Reopen())
+			l.Info("third")
+
+			lines := readLines(t, filename+".1")
+			assert.Len(t, lines, 2)
+			lines = readLines(t, filename)
+			if assert.Len(t, lines, 1) {
+				assert.Contains(t, lines[0], `msg=third`)
+			}
+		})
+	}
+}
+
+func TestOptionsValidateDisableRotation(t *testing.T) {
+	err := Options{DisableRotation: true, Rotation: RotationDaily}.Validate()
+	assert.EqualError(t, err, `disable_rotation: conflicts with rotation and compress; filename: required when rotation is disabled`)
+}
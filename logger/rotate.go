@@ -0,0 +1,434 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RotationHourly = "hourly"
+	RotationDaily  = "daily"
+
+	CompressGzip = "gzip"
+
+	megabyte = 1024 * 1024
+
+	compressSuffix = ".gz"
+)
+
+var defaultRotationPatterns = map[string]string{
+	"":             "2006-01-02T15-04-05.000",
+	RotationHourly: "2006-01-02-15",
+	RotationDaily:  "2006-01-02",
+}
+
+// rotateWriter 按时间周期切割日志文件，同时支持按大小切割
+// 当前写入的文件始终为 filename，切割后的备份文件名为 filename.<pattern>[.N][.gz]
+type rotateWriter struct {
+	filename      string
+	rotation      string
+	pattern       string
+	maxSize       int64
+	maxAge        time.Duration
+	maxBackups    int
+	compress      bool
+	compressLevel int
+
+	mut    sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time
+
+	// millCh 和 millDone 在第一次切割时创建，Close 之后不再启动清理
+	millCh   chan struct{}
+	millDone chan struct{}
+	millMut  sync.Mutex
+	closed   bool
+
+	// now 便于测试时替换
+	now func() time.Time
+}
+
+func newRotateWriter(sink SinkOptions) (*rotateWriter, error) {
+	pattern, ok := defaultRotationPatterns[sink.Rotation]
+	if !ok {
+		return nil, fmt.Errorf("unknown rotation: %q", sink.Rotation)
+	}
+	if sink.RotationPattern != "" {
+		pattern = sink.RotationPattern
+	}
+
+	level := sink.CompressLevel
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	switch sink.Compress {
+	case "", CompressGzip:
+	default:
+		return nil, fmt.Errorf("unknown compress: %q", sink.Compress)
+	}
+	if _, err := gzip.NewWriterLevel(ioutil.Discard, level); err != nil {
+		return nil, err
+	}
+
+	return &rotateWriter{
+		filename:      sink.Filename,
+		rotation:      sink.Rotation,
+		pattern:       pattern,
+		maxSize:       int64(sink.MaxSize) * megabyte,
+		maxAge:        time.Duration(sink.MaxAge) * 24 * time.Hour,
+		maxBackups:    sink.MaxBackups,
+		compress:      sink.Compress == CompressGzip,
+		compressLevel: level,
+		now:           time.Now,
+	}, nil
+}
+
+// Write implements io.Writer, it returns os.ErrClosed after Close instead
+// of opening the file again.
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		if err := w.openExisting(); err != nil {
+			return 0, err
+		}
+	}
+
+	if !w.periodOf(w.now()).Equal(w.period) || (w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync implements zapcore.WriteSyncer
+func (w *rotateWriter) Sync() error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close implements io.Closer, it waits for the running cleanup and
+// compression of backups so that no half-written file is left behind.
+func (w *rotateWriter) Close() error {
+	w.mut.Lock()
+	err := w.close()
+	w.closed = true
+	if w.millCh != nil {
+		close(w.millCh)
+		w.millCh = nil
+	}
+	done := w.millDone
+	w.mut.Unlock()
+
+	if done != nil {
+		<-done
+	}
+	return err
+}
+
+func (w *rotateWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// periodOf 返回 t 所属切割周期的起始时间，仅按大小切割时返回零值
+func (w *rotateWriter) periodOf(t time.Time) time.Time {
+	t = t.Local()
+	switch w.rotation {
+	case RotationHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case RotationDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+// openExisting 打开已有的日志文件继续写入，文件所属周期以修改时间为准
+func (w *rotateWriter) openExisting() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), os.ModePerm); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(w.filename)
+	if os.IsNotExist(err) {
+		return w.openNew()
+	}
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(w.filename, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.period = w.periodOf(info.ModTime())
+	return nil
+}
+
+func (w *rotateWriter) openNew() error {
+	f, err := os.OpenFile(w.filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.size = 0
+	w.period = w.periodOf(w.now())
+	return nil
+}
+
+// rotate 将当前文件重命名为带时间戳的备份文件并打开新文件
+func (w *rotateWriter) rotate() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	stamp := w.period
+	if stamp.IsZero() {
+		stamp = w.now()
+	}
+	if err := os.Rename(w.filename, w.backupName(stamp)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.openNew(); err != nil {
+		return err
+	}
+
+	w.mill()
+	return nil
+}
+
+// backupName 返回周期对应的第一个未被占用的备份文件名，压缩后的文件同样视为占用
+func (w *rotateWriter) backupName(period time.Time) string {
+	name := w.filename + "." + period.Format(w.pattern)
+	candidate := name
+	for i := 1; ; i++ {
+		if !exists(candidate) && !exists(candidate+compressSuffix) {
+			return candidate
+		}
+		candidate = name + "." + strconv.Itoa(i)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
+
+// mill 在后台清理过期的备份文件并压缩，不阻塞日志写入，调用时需持有 w.mut
+func (w *rotateWriter) mill() {
+	if w.closed {
+		return
+	}
+	if w.millCh == nil {
+		w.millCh = make(chan struct{}, 1)
+		w.millDone = make(chan struct{})
+		go w.millRun(w.millCh, w.millDone)
+	}
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+func (w *rotateWriter) millRun(ch <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	for range ch {
+		_ = w.millRunOnce()
+	}
+}
+
+type backupFile struct {
+	path       string
+	period     time.Time
+	index      int
+	compressed bool
+}
+
+// millRunOnce 先按数量和时间清理备份文件（包括已压缩的），再压缩剩余未压缩的备份
+func (w *rotateWriter) millRunOnce() error {
+	if w.maxAge <= 0 && w.maxBackups <= 0 && !w.compress {
+		return nil
+	}
+
+	w.millMut.Lock()
+	defer w.millMut.Unlock()
+
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var remove []backupFile
+	if w.maxBackups > 0 && len(backups) > w.maxBackups {
+		remove = append(remove, backups[w.maxBackups:]...)
+		backups = backups[:w.maxBackups]
+	}
+	if w.maxAge > 0 {
+		cutoff := w.now().Add(-w.maxAge)
+		if period := w.periodOf(cutoff); !period.IsZero() {
+			cutoff = period
+		}
+
+		remain := backups[:0]
+		for _, b := range backups {
+			if b.period.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				remain = append(remain, b)
+			}
+		}
+		backups = remain
+	}
+
+	for _, b := range remove {
+		if e := os.Remove(b.path); e != nil && !os.IsNotExist(e) && err == nil {
+			err = e
+		}
+	}
+
+	if !w.compress {
+		return err
+	}
+	for _, b := range backups {
+		if b.compressed {
+			continue
+		}
+		if e := compressFile(b.path, w.compressLevel); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// compressFile 将 src 压缩为 src.gz，成功后删除 src
+func compressFile(src string, level int) (err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dst := src + compressSuffix
+	gzf, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			gzf.Close()
+			os.Remove(dst)
+		}
+	}()
+
+	gz, err := gzip.NewWriterLevel(gzf, level)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(gz, f); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = gzf.Close(); err != nil {
+		return err
+	}
+
+	f.Close()
+	return os.Remove(src)
+}
+
+// backups 返回所有备份文件，按时间从新到旧排序
+func (w *rotateWriter) backups() ([]backupFile, error) {
+	files, err := ioutil.ReadDir(filepath.Dir(w.filename))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(w.filename) + "."
+	var backups []backupFile
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), prefix) {
+			continue
+		}
+		if b, ok := w.parseBackup(f.Name()[len(prefix):]); ok {
+			b.path = filepath.Join(filepath.Dir(w.filename), f.Name())
+			backups = append(backups, b)
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].period.Equal(backups[j].period) {
+			return backups[i].period.After(backups[j].period)
+		}
+		return backups[i].index > backups[j].index
+	})
+	return backups, nil
+}
+
+// parseBackup 解析备份文件名中 filename. 之后的部分，格式为 <pattern>[.N][.gz]
+func (w *rotateWriter) parseBackup(suffix string) (backupFile, bool) {
+	var b backupFile
+	if strings.HasSuffix(suffix, compressSuffix) {
+		suffix = strings.TrimSuffix(suffix, compressSuffix)
+		b.compressed = true
+	}
+	if t, err := time.ParseInLocation(w.pattern, suffix, time.Local); err == nil {
+		b.period = t
+		return b, true
+	}
+
+	i := strings.LastIndexByte(suffix, '.')
+	if i <= 0 {
+		return b, false
+	}
+	n, err := strconv.Atoi(suffix[i+1:])
+	if err != nil {
+		return b, false
+	}
+	t, err := time.ParseInLocation(w.pattern, suffix[:i], time.Local)
+	if err != nil {
+		return b, false
+	}
+	b.period, b.index = t, n
+	return b, true
+}
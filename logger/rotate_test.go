@@ -0,0 +1,197 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time      { return c.t }
+func (c *fakeClock) add(d time.Duration) { c.t = c.t.Add(d) }
+
+func readFile(t *testing.T, p string) string {
+	b, err := ioutil.ReadFile(p)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func newTestRotateWriter(t *testing.T, sink SinkOptions) (*rotateWriter, *fakeClock) {
+	w, err := newRotateWriter(sink)
+	assert.NoError(t, err)
+	clock := &fakeClock{t: time.Date(2026, 10, 18, 13, 30, 0, 0, time.Local)}
+	w.now = clock.now
+	t.Cleanup(func() { w.Close() })
+	return w, clock
+}
+
+func TestRotateWriterHourly(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "applog")
+	w, clock := newTestRotateWriter(t, SinkOptions{Filename: filename, Rotation: RotationHourly})
+
+	_, err := w.Write([]byte("first\n"))
+	assert.NoError(t, err)
+
+	clock.add(time.Hour)
+	_, err = w.Write([]byte("second\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "first\n", readFile(t, filename+".2026-10-18-13"))
+	assert.Equal(t, "second\n", readFile(t, filename))
+}
+
+func TestRotateWriterDailyWithSize(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "applog")
+	w, clock := newTestRotateWriter(t, SinkOptions{Filename: filename, Rotation: RotationDaily})
+	w.maxSize = 10
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n"} {
+		_, err := w.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+
+	clock.add(24 * time.Hour)
+	_, err := w.Write([]byte("dddddddd\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "aaaaaaaa\n", readFile(t, filename+".2026-10-18"))
+	assert.Equal(t, "bbbbbbbb\n", readFile(t, filename+".2026-10-18.1"))
+	assert.Equal(t, "cccccccc\n", readFile(t, filename+".2026-10-18.2"))
+	assert.Equal(t, "dddddddd\n", readFile(t, filename))
+}
+
+func TestRotateWriterCleanup(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "applog")
+	w, clock := newTestRotateWriter(t, SinkOptions{Filename: filename, Rotation: RotationHourly, MaxBackups: 2})
+
+	for i := 0; i < 4; i++ {
+		_, err := w.Write([]byte("line\n"))
+		assert.NoError(t, err)
+		clock.add(time.Hour)
+	}
+	assert.NoError(t, w.millRunOnce())
+
+	backups, err := w.backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+	assert.Equal(t, filename+".2026-10-18-15", backups[0].path)
+	assert.Equal(t, filename+".2026-10-18-14", backups[1].path)
+}
+
+func TestRotateWriterUnknownRotation(t *testing.T) {
+	_, err := newRotateWriter(SinkOptions{Filename: "applog", Rotation: "weekly"})
+	assert.Error(t, err)
+}
+
+func TestRotateWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "applog")
+	w, clock := newTestRotateWriter(t, SinkOptions{
+		Filename:      filename,
+		Rotation:      RotationHourly,
+		MaxBackups:    2,
+		Compress:      CompressGzip,
+		CompressLevel: gzip.BestSpeed,
+	})
+
+	for i := 0; i < 4; i++ {
+		_, err := w.Write([]byte("line\n"))
+		assert.NoError(t, err)
+		clock.add(time.Hour)
+	}
+	assert.NoError(t, w.millRunOnce())
+
+	backups, err := w.backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+	for _, b := range backups {
+		assert.True(t, b.compressed, b.path)
+	}
+
+	f, err := os.Open(filename + ".2026-10-18-15.gz")
+	assert.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, "line\n", string(b))
+}
+
+func TestRotateWriterSizeOnlyCompress(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "applog")
+	w, _ := newTestRotateWriter(t, SinkOptions{Filename: filename, Compress: CompressGzip})
+	w.maxSize = 10
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n"} {
+		_, err := w.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.millRunOnce())
+
+	backups, err := w.backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	assert.Equal(t, filename+".2026-10-18T13-30-00.000.gz", backups[0].path)
+	assert.Equal(t, "bbbbbbbb\n", readFile(t, filename))
+}
+
+func TestRotateWriterCloseWaitsMill(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "applog")
+	before := runtime.NumGoroutine()
+	w, clock := newTestRotateWriter(t, SinkOptions{Filename: filename, Rotation: RotationHourly, Compress: CompressGzip})
+
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("line\n"))
+		assert.NoError(t, err)
+		clock.add(time.Hour)
+	}
+	assert.NoError(t, w.Close())
+
+	// Close 返回时压缩已经完成，后台 goroutine 也已退出
+	backups, err := w.backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+	for _, b := range backups {
+		assert.True(t, b.compressed, b.path)
+	}
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+
+	// Close 之后的写入返回错误，不会重新打开文件或启动新的 goroutine
+	clock.add(time.Hour)
+	_, err = w.Write([]byte("line\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.Nil(t, w.file)
+	assert.Nil(t, w.millCh)
+	backups, err = w.backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+}
+
+func TestRotateWriterUnknownCompress(t *testing.T) {
+	_, err := newRotateWriter(SinkOptions{Filename: "applog", Compress: "zstd"})
+	assert.Error(t, err)
+}
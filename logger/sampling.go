@@ -0,0 +1,131 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultSamplingTick           = time.Second
+	defaultSamplingInitial        = 100
+	defaultSamplingThereafter     = 100
+	defaultSamplingReportInterval = time.Minute
+)
+
+// SamplingOptions is the option set for log sampling. Within each Tick, the
+// first Initial entries with the same level and message are logged, after
+// that only every Thereafter-th entry is logged.
+type SamplingOptions struct {
+	// Tick is the sampling period, default is 1s.
+	Tick time.Duration `yaml:"tick"`
+
+	// Initial is the number of entries logged per message per tick, default is 100.
+	Initial int `yaml:"initial"`
+
+	// Thereafter logs every Mth entry after Initial is exceeded, default is 100.
+	Thereafter int `yaml:"thereafter"`
+
+	// ReportInterval is how often a summary of dropped entries is logged,
+	// default is 1m.
+	ReportInterval time.Duration `yaml:"report_interval"`
+}
+
+// samplingReporter 统计被采样丢弃的日志数量，并定期通过未采样的 core 输出汇总日志
+type samplingReporter struct {
+	core     zapcore.Core
+	interval time.Duration
+	dropped  uint64
+
+	stopOnce sync.Once
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// newSampledCore 为 core 添加采样功能，返回的 reporter 需要在不再使用时 stop
+func newSampledCore(core zapcore.Core, opt SamplingOptions) (zapcore.Core, *samplingReporter) {
+	if opt.Tick <= 0 {
+		opt.Tick = defaultSamplingTick
+	}
+	if opt.Initial <= 0 {
+		opt.Initial = defaultSamplingInitial
+	}
+	if opt.Thereafter <= 0 {
+		opt.Thereafter = defaultSamplingThereafter
+	}
+	if opt.ReportInterval <= 0 {
+		opt.ReportInterval = defaultSamplingReportInterval
+	}
+
+	r := &samplingReporter{
+		core:     core,
+		interval: opt.ReportInterval,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go r.run()
+
+	sampled := zapcore.NewSamplerWithOptions(core, opt.Tick, opt.Initial, opt.Thereafter, zapcore.SamplerHook(r.hook))
+	return sampled, r
+}
+
+func (r *samplingReporter) hook(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+	if dec&zapcore.LogDropped > 0 {
+		atomic.AddUint64(&r.dropped, 1)
+	}
+}
+
+func (r *samplingReporter) run() {
+	defer close(r.stopped)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			r.report()
+			return
+		case <-ticker.C:
+			r.report()
+		}
+	}
+}
+
+// report 输出上一个周期内被丢弃的日志数量
+func (r *samplingReporter) report() {
+	n := atomic.SwapUint64(&r.dropped, 0)
+	if n == 0 {
+		return
+	}
+
+	ent := zapcore.Entry{
+		Level:   zapcore.WarnLevel,
+		Time:    time.Now(),
+		Message: "log entries dropped by sampler",
+	}
+	if ce := r.core.Check(ent, nil); ce != nil {
+		ce.Write(zap.Uint64("dropped", n), zap.Duration("interval", r.interval))
+	}
+}
+
+// stop 停止后台任务，返回前会输出剩余的汇总
+func (r *samplingReporter) stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+	<-r.stopped
+}
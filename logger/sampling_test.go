@@ -0,0 +1,48 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSampledCore(t *testing.T) {
+	l, buf := newBufferLogger(DebugLevel)
+
+	core, reporter := newSampledCore(l.sugared.Desugar().Core(), SamplingOptions{
+		Tick:           time.Minute,
+		Initial:        2,
+		Thereafter:     3,
+		ReportInterval: time.Hour,
+	})
+	sugared := zap.New(core).Sugar()
+
+	for i := 0; i < 10; i++ {
+		sugared.Warnf("hostid file broken")
+	}
+	sugared.Info("other message")
+
+	// 前 2 条全部输出，之后每 3 条输出 1 条: 第 5、8 条
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 5)
+	assert.Equal(t, "level=info msg=\"other message\"", lines[4])
+
+	buf.Reset()
+	reporter.stop()
+	assert.Eventually(t, func() bool {
+		return buf.String() == "level=warn msg=\"log entries dropped by sampler\" dropped=6 interval=1h0m0s\n"
+	}, time.Second, 10*time.Millisecond)
+}
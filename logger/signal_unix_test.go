@@ -0,0 +1,59 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+//go:build !windows
+// +build !windows
+
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerReloadOnSignal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	l := New(Options{Filename: filename})
+	defer l.Close()
+
+	stop := l.ReloadOnSignal(func() (Options, error) {
+		return Options{Level: "error", Filename: filename}, nil
+	})
+	defer stop()
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+	assert.Eventually(t, func() bool { return l.GetLevel() == ErrorLevel }, time.Second, 10*time.Millisecond)
+}
+
+func TestLoggerReopenOnSignal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	l := New(Options{Filename: filename, DisableRotation: true})
+	defer l.Close()
+
+	stop := l.ReopenOnSignal()
+	defer stop()
+
+	l.Info("before rotate")
+	assert.NoError(t, os.Rename(filename, filename+".1"))
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filename)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	l.Info("after rotate")
+	assert.Len(t, readLines(t, filename+".1"), 1)
+	assert.Len(t, readLines(t, filename), 1)
+}
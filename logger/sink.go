@@ -0,0 +1,196 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	SinkStdout = "stdout"
+	SinkStderr = "stderr"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+	SinkNet    = "net"
+)
+
+// SinkOptions is the option set for one output of Logger.
+type SinkOptions struct {
+	// Type is the kind of output. Valid values are "stdout", "stderr", "file",
+	// "syslog" and "net".
+	Type string `yaml:"type"`
+
+	// Level is the minimum level written to this sink. Entries must also pass
+	// the level of the Logger itself. Empty means no extra filtering.
+	Level string `yaml:"level"`
+
+	// Format is the encoder of this sink, Valid values are "json", "console"
+	// and "logfmt". Empty means inheriting Options.Format.
+	Format string `yaml:"format"`
+
+	// Filename is the file to write logs to when Type is "file".
+	Filename string `yaml:"filename"`
+
+	// MaxSize is the maximum size in megabytes of the log file before it gets rotated.
+	MaxSize int `yaml:"max_size"`
+
+	// MaxAge is the maximum number of days to retain old log files.
+	MaxAge int `yaml:"max_age"`
+
+	// MaxBackups is the maximum number of old log files to retain.
+	MaxBackups int `yaml:"max_backups"`
+
+	// Rotation enables time based rotation, Valid values are "hourly" and
+	// "daily". It can be combined with MaxSize. Empty means size based only.
+	Rotation string `yaml:"rotation"`
+
+	// RotationPattern is the time layout appended to the backup filename,
+	// default is "2006-01-02-15" for hourly, "2006-01-02" for daily and
+	// "2006-01-02T15-04-05.000" when files are only rotated by size.
+	RotationPattern string `yaml:"rotation_pattern"`
+
+	// Compress is the algorithm used to compress rotated files in the
+	// background, Valid value is "gzip". Empty means no compression.
+	Compress string `yaml:"compress"`
+
+	// CompressLevel is the compression level, 0 means gzip.DefaultCompression.
+	CompressLevel int `yaml:"compress_level"`
+
+	// DisableRotation makes the sink only append to Filename, leaving the
+	// rotation to external tools such as logrotate. MaxSize, MaxAge and
+	// MaxBackups are ignored and Rotation and Compress must be empty. Use
+	// Reopen or ReopenOnSignal after the file is renamed.
+	DisableRotation bool `yaml:"disable_rotation"`
+
+	// Syslog is the option set when Type is "syslog".
+	Syslog SyslogOptions `yaml:"syslog"`
+
+	// Net is the option set when Type is "net".
+	Net NetOptions `yaml:"net"`
+}
+
+// consoleSyncer 标准输出不需要也不一定支持 fsync，Sync 为空操作且不会被关闭
+type consoleSyncer struct {
+	io.Writer
+}
+
+func (consoleSyncer) Sync() error { return nil }
+
+// lumberjackSyncer 为 lumberjack.Logger 补充 Sync，同时保留 Close
+type lumberjackSyncer struct {
+	*lumberjack.Logger
+}
+
+func (lumberjackSyncer) Sync() error { return nil }
+
+func newEncoder(format string, cfg zapcore.EncoderConfig, logfmtConfig bool) zapcore.Encoder {
+	switch format {
+	case "json":
+		return zapcore.NewJSONEncoder(cfg)
+	case "console":
+		return zapcore.NewConsoleEncoder(cfg)
+	}
+
+	var opts []LogfmtOption
+	if logfmtConfig {
+		opts = append(opts, LogfmtConfigEncoders())
+	}
+	return NewLogfmtEncoder(cfg, opts...)
+}
+
+func newWriteSyncer(sink SinkOptions) (zapcore.WriteSyncer, error) {
+	switch sink.Type {
+	case SinkStdout:
+		return consoleSyncer{os.Stdout}, nil
+	case SinkStderr:
+		return consoleSyncer{os.Stderr}, nil
+	case SinkSyslog:
+		return newSyslogConn(sink.Syslog), nil
+	case SinkNet:
+		return newNetWriter(sink.Net)
+	case SinkFile, "":
+		// 初始化日志目录
+		if err := os.MkdirAll(filepath.Dir(sink.Filename), os.ModePerm); err != nil {
+			return nil, err
+		}
+
+		if sink.DisableRotation {
+			return newFileWriter(sink.Filename), nil
+		}
+		if sink.Rotation != "" || sink.Compress != "" {
+			w, err := newRotateWriter(sink)
+			if err != nil {
+				return nil, err
+			}
+			return w, nil
+		}
+
+		return lumberjackSyncer{&lumberjack.Logger{
+			Filename:   sink.Filename,
+			MaxSize:    sink.MaxSize,
+			MaxBackups: sink.MaxBackups,
+			MaxAge:     sink.MaxAge,
+			LocalTime:  true,
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type: %q", sink.Type)
+	}
+}
+
+// newSinkCore 构造单个输出的 core，这里只处理 sink 级别，logger 级别由外层的 levelCore 处理；m 为空时不统计
+func newSinkCore(sink SinkOptions, opt Options, cfg zapcore.EncoderConfig, res *resources, m *metrics) (zapcore.Core, error) {
+	format := opt.Format
+	if sink.Format != "" {
+		format = sink.Format
+	}
+
+	w, err := newWriteSyncer(sink)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := w.(io.Closer); ok {
+		res.closers = append(res.closers, c)
+	}
+	if r, ok := w.(reopener); ok {
+		res.reopeners = append(res.reopeners, r)
+	}
+	if nw, ok := w.(*netWriter); ok {
+		res.netWriters = append(res.netWriters, nw)
+	}
+	if m != nil {
+		w = countingWriter{WriteSyncer: w, m: m}
+	}
+	if opt.Async != nil {
+		aw, err := newAsyncWriter(w, *opt.Async)
+		if err != nil {
+			return nil, err
+		}
+		res.asyncWriters = append(res.asyncWriters, aw)
+		w = aw
+	}
+
+	var enabler zapcore.LevelEnabler = zapcore.DebugLevel
+	if sink.Level != "" {
+		lvl, _ := ParseLevel(sink.Level)
+		enabler = zapcore.Level(lvl)
+	}
+
+	if sink.Type == SinkSyslog {
+		return newSyslogCore(sink.Syslog, newEncoder(format, cfg, opt.LogfmtEncoderConfig), w, enabler), nil
+	}
+	return zapcore.NewCore(newEncoder(format, cfg, opt.LogfmtEncoderConfig), w, enabler), nil
+}
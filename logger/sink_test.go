@@ -0,0 +1,84 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerMultipleSinks(t *testing.T) {
+	dir := t.TempDir()
+	appLog := filepath.Join(dir, "applog")
+	errLog := filepath.Join(dir, "error.log")
+
+	l := New(Options{
+		Level:  "info",
+		Format: "logfmt",
+		Sinks: []SinkOptions{
+			{Type: SinkFile, Filename: appLog},
+			{Type: SinkFile, Filename: errLog, Level: "error", Format: "json"},
+		},
+	})
+
+	l.Debug("debug message")
+	l.Info("info message")
+	l.Errorw("error message", "code", 500)
+
+	b, err := ioutil.ReadFile(appLog)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `msg="info message"`)
+	assert.Contains(t, lines[1], `msg="error message" code=500`)
+
+	b, err = ioutil.ReadFile(errLog)
+	assert.NoError(t, err)
+	lines = strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"msg":"error message"`)
+	assert.Contains(t, lines[0], `"code":500`)
+}
+
+func TestLoggerLogfmtEncoderConfig(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		enabled bool
+		pattern string
+	}{
+		{false, `^ts=\d{4}-\d\d-\d\d \d\d:\d\d:\d\d\.\d{3} level=info caller=\S+ msg=done cost=1.5s$`},
+		{true, `^ts="\d{4}-\d\d-\d\d \d\d:\d\d:\d\d\.\d{3}" level=INFO caller=\S+ msg=done cost=1.5$`},
+	} {
+		filename := filepath.Join(dir, "app.log")
+		if tc.enabled {
+			filename = filepath.Join(dir, "config.log")
+		}
+		l := New(Options{Level: "info", Filename: filename, LogfmtEncoderConfig: tc.enabled})
+		l.Infow("done", "cost", 1500*time.Millisecond)
+		assert.NoError(t, l.Close())
+
+		lines := readLines(t, filename)
+		assert.Len(t, lines, 1)
+		assert.Regexp(t, regexp.MustCompile(tc.pattern), lines[0])
+	}
+}
+
+func TestLoggerUnknownSink(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Options{Sinks: []SinkOptions{{Type: "kafka"}}})
+	})
+}
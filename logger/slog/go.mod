@@ -0,0 +1,25 @@
+module github.com/TencentBlueKing/bkmonitor-kits/logger/slog
+
+go 1.21
+
+require (
+	github.com/TencentBlueKing/bkmonitor-kits v0.1.0
+	github.com/stretchr/testify v1.7.0
+	go.uber.org/zap v1.21.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logfmt/logfmt v0.5.0 // indirect
+	github.com/google/go-cmp v0.5.9 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel v1.0.0 // indirect
+	go.opentelemetry.io/otel/trace v1.0.0 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
+)
+
+// 开发时使用同一仓库中的 logger。replace 只在本 module 内生效，使用方 go get 时按上面 require 的版本获取，发布顺序见 README
+replace github.com/TencentBlueKing/bkmonitor-kits => ../..
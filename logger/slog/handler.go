@@ -0,0 +1,196 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+// Package slog provides a log/slog Handler writing through logger.Logger,
+// so that code using slog shares the format, sinks and level of the kits
+// logger.
+//
+//	import bkslog "github.com/TencentBlueKing/bkmonitor-kits/logger/slog"
+//
+//	slog.SetDefault(slog.New(bkslog.NewHandler(logger.StandardLogger(), nil)))
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/TencentBlueKing/bkmonitor-kits/logger"
+)
+
+// HandlerOptions is the option set for Handler.
+type HandlerOptions struct {
+	// Level maps slog levels, including custom ones, to logger levels.
+	// Default is DefaultLevel. Mapping to logger.PanicLevel or
+	// logger.FatalLevel makes the entry panic or exit the process.
+	Level func(slog.Level) logger.Level
+}
+
+// DefaultLevel maps each slog level to the closest logger level not above
+// it: below slog.LevelInfo is debug, below slog.LevelWarn is info, below
+// slog.LevelError is warn and the rest is error.
+func DefaultLevel(level slog.Level) logger.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return logger.DebugLevel
+	case level < slog.LevelWarn:
+		return logger.InfoLevel
+	case level < slog.LevelError:
+		return logger.WarnLevel
+	default:
+		return logger.ErrorLevel
+	}
+}
+
+// Handler implements slog.Handler on top of logger.Logger. Groups become
+// namespaces of the entry, e.g. nested JSON objects, and the caller is taken
+// from the record.
+type Handler struct {
+	logger *zap.Logger
+	level  func(slog.Level) logger.Level
+
+	// 尚未输出的 group，只有在 group 中存在属性时才创建 namespace
+	groups []string
+}
+
+// NewHandler returns a Handler writing to l, opts can be nil.
+func NewHandler(l logger.Logger, opts *HandlerOptions) *Handler {
+	h := &Handler{
+		// caller 由 Record.PC 决定
+		logger: l.Desugar().WithOptions(zap.WithCaller(false)),
+		level:  DefaultLevel,
+	}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Core().Enabled(zapcore.Level(h.level(level)))
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	ce := h.logger.Check(zapcore.Level(h.level(r.Level)), r.Message)
+	if ce == nil {
+		return nil
+	}
+	ce.Entry.Time = r.Time
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Entry.Caller = zapcore.EntryCaller{
+			Defined:  true,
+			PC:       frame.PC,
+			File:     frame.File,
+			Line:     frame.Line,
+			Function: frame.Function,
+		}
+	}
+
+	fields := make([]zapcore.Field, 0, len(h.groups)+r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+	if len(fields) > 0 {
+		fields = append(h.namespaces(), fields...)
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []zapcore.Field
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	clone := *h
+	clone.logger = h.logger.With(append(h.namespaces(), fields...)...)
+	clone.groups = nil
+	return &clone
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &clone
+}
+
+func (h *Handler) namespaces() []zapcore.Field {
+	fields := make([]zapcore.Field, 0, len(h.groups))
+	for _, name := range h.groups {
+		fields = append(fields, zap.Namespace(name))
+	}
+	return fields
+}
+
+// appendAttr 将 slog 属性转换为 zap 字段，忽略空属性和空 group，key 为空的 group 展开到上一层
+func appendAttr(fields []zapcore.Field, a slog.Attr) []zapcore.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, a.Value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, a.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, a.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, a.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, a.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, a.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, a.Value.Time()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, sub := range attrs {
+				fields = appendAttr(fields, sub)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, groupObject(attrs)))
+	default:
+		return append(fields, zap.Any(a.Key, a.Value.Any()))
+	}
+}
+
+// groupObject 将 group 中的属性输出为嵌套对象
+type groupObject []slog.Attr
+
+func (g groupObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, a := range g {
+		for _, f := range appendAttr(nil, a) {
+			f.AddTo(enc)
+		}
+	}
+	return nil
+}
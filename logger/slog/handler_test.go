@@ -0,0 +1,109 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"testing/slogtest"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/TencentBlueKing/bkmonitor-kits/logger"
+)
+
+func TestSlogtest(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	h := NewHandler(logger.NewWithCore(core), nil)
+
+	results := func() []map[string]interface{} {
+		var ms []map[string]interface{}
+		for _, e := range logs.All() {
+			m := e.ContextMap()
+			if !e.Time.IsZero() {
+				m[slog.TimeKey] = e.Time
+			}
+			m[slog.LevelKey] = e.Level
+			m[slog.MessageKey] = e.Message
+			ms = append(ms, m)
+		}
+		return ms
+	}
+	assert.NoError(t, slogtest.TestHandler(h, results))
+}
+
+type token string
+
+func (token) LogValue() slog.Value {
+	return slog.StringValue("******")
+}
+
+func TestHandlerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := zapcore.EncoderConfig{MessageKey: "msg", LevelKey: "level", EncodeLevel: zapcore.LowercaseLevelEncoder}
+	l := logger.NewWithCore(zapcore.NewCore(zapcore.NewJSONEncoder(cfg), zapcore.AddSync(&buf), zapcore.DebugLevel))
+
+	sl := slog.New(NewHandler(l, nil)).With("service", "api").WithGroup("req")
+	sl.Info("request done", "path", "/v1", slog.Group("user", "name", "admin", "token", token("secret")),
+		"error", errors.New("timeout"))
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, map[string]interface{}{
+		"level":   "info",
+		"msg":     "request done",
+		"service": "api",
+		"req": map[string]interface{}{
+			"path":  "/v1",
+			"user":  map[string]interface{}{"name": "admin", "token": "******"},
+			"error": "timeout",
+		},
+	}, m)
+}
+
+func TestHandlerLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := logger.NewWithCore(core)
+	l.SetLevel(logger.InfoLevel)
+
+	const levelNotice = slog.Level(2)
+	sl := slog.New(NewHandler(l, nil))
+	assert.False(t, sl.Enabled(context.Background(), slog.LevelDebug))
+	sl.Debug("filtered")
+	sl.Log(context.Background(), levelNotice, "notice")
+	sl.Warn("warn")
+	sl.Log(context.Background(), slog.LevelError+4, "critical")
+
+	levels := make([]zapcore.Level, 0, logs.Len())
+	for _, e := range logs.All() {
+		levels = append(levels, e.Level)
+	}
+	assert.Equal(t, []zapcore.Level{zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel}, levels)
+	assert.Equal(t, "handler_test.go", filepath.Base(logs.All()[0].Caller.File))
+
+	// 自定义映射
+	logs.TakeAll()
+	sl = slog.New(NewHandler(l, &HandlerOptions{Level: func(level slog.Level) logger.Level {
+		if level > slog.LevelError {
+			return logger.DPanicLevel
+		}
+		return DefaultLevel(level)
+	}}))
+	sl.Log(context.Background(), slog.LevelError+4, "critical")
+	assert.Equal(t, zapcore.DPanicLevel, logs.All()[0].Level)
+}
@@ -0,0 +1,99 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"bytes"
+	"log"
+	"runtime"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// StdLogger returns a *log.Logger for libraries which only accept one. Each
+// line it writes becomes an entry of l at level, with the caller set to the
+// code calling the *log.Logger.
+func (l Logger) StdLogger(level Level) *log.Logger {
+	return log.New(l.stdLogWriter(level), "", 0)
+}
+
+// RedirectStdLog sends the output of the global logger of the log package to
+// the standard logger at info level. Call restore to put back the previous
+// output, flags and prefix.
+func RedirectStdLog() (restore func()) {
+	return RedirectStdLogAt(InfoLevel)
+}
+
+// RedirectStdLogAt is like RedirectStdLog with the entries written at level.
+func RedirectStdLogAt(level Level) (restore func()) {
+	flags, prefix, out := log.Flags(), log.Prefix(), log.Writer()
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(std.stdLogWriter(level))
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			log.SetFlags(flags)
+			log.SetPrefix(prefix)
+			log.SetOutput(out)
+		})
+	}
+}
+
+func (l Logger) stdLogWriter(level Level) *stdLogWriter {
+	return &stdLogWriter{
+		// caller 在 Write 中根据调用栈计算
+		logger: l.sugared.Desugar().WithOptions(zap.WithCaller(false)),
+		level:  zapcore.Level(level),
+	}
+}
+
+// stdLogWriter 将 log.Logger 输出的每一行转换为一条日志
+type stdLogWriter struct {
+	logger *zap.Logger
+	level  zapcore.Level
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimSpace(p))
+	if ce := w.logger.Check(w.level, msg); ce != nil {
+		ce.Entry.Caller = stdLogCaller()
+		ce.Write()
+	}
+	return len(p), nil
+}
+
+// stdLogCaller 跳过 log 包内部的调用，返回调用 log 包的位置；log 包在不同的入口下调用深度不同，因此不使用固定的深度
+func stdLogCaller() zapcore.EntryCaller {
+	pcs := make([]uintptr, 16)
+	// 跳过 runtime.Callers、stdLogCaller 和 stdLogWriter.Write
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "log.") {
+			return zapcore.EntryCaller{
+				Defined:  frame.PC != 0,
+				PC:       frame.PC,
+				File:     frame.File,
+				Line:     frame.Line,
+				Function: frame.Function,
+			}
+		}
+		if !more {
+			return zapcore.EntryCaller{}
+		}
+	}
+}
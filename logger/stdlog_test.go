@@ -0,0 +1,84 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"log"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// currentLine 返回调用处的行号
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestStdLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := NewWithCore(core).With("component", "consul")
+
+	stdLogger := l.StdLogger(WarnLevel)
+	stdLogger.Printf("agent %s unreachable\n", "127.0.0.1")
+	line := currentLine() - 1
+
+	entries := logs.All()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+		assert.Equal(t, "agent 127.0.0.1 unreachable", entries[0].Message)
+		assert.Equal(t, "stdlog_test.go", filepath.Base(entries[0].Caller.File))
+		assert.Equal(t, line, entries[0].Caller.Line)
+		assert.Equal(t, map[string]interface{}{"component": "consul"}, entries[0].ContextMap())
+	}
+
+	// 低于 logger 级别的输出被丢弃
+	l.SetLevel(ErrorLevel)
+	stdLogger.Print("dropped")
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	defer ReplaceStandardLogger(NewWithCore(core))()
+
+	out := log.Writer()
+	log.SetPrefix("lib: ")
+	defer log.SetPrefix("")
+
+	restore := RedirectStdLog()
+	log.Println("from print")
+	printLine := currentLine() - 1
+	log.Output(1, "from output")
+	outputLine := currentLine() - 1
+	restore()
+	restore()
+
+	entries := logs.All()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+		assert.Equal(t, "from print", entries[0].Message)
+		assert.Equal(t, printLine, entries[0].Caller.Line)
+		assert.Equal(t, "from output", entries[1].Message)
+		assert.Equal(t, outputLine, entries[1].Caller.Line)
+	}
+	assert.Equal(t, out, log.Writer())
+	assert.Equal(t, "lib: ", log.Prefix())
+
+	restore = RedirectStdLogAt(ErrorLevel)
+	log.Print("failed")
+	restore()
+	assert.Equal(t, zapcore.ErrorLevel, logs.All()[2].Level)
+}
@@ -0,0 +1,256 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	SyslogRFC5424 = "rfc5424"
+	SyslogRFC3164 = "rfc3164"
+
+	defaultSyslogFacility = "user"
+)
+
+// 本地 syslog 常见的 unix socket 路径，与 log/syslog 一致
+var syslogLocalAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogSeverities 将日志级别映射为 syslog severity
+var syslogSeverities = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7, // debug
+	zapcore.InfoLevel:   6, // info
+	zapcore.WarnLevel:   4, // warning
+	zapcore.ErrorLevel:  3, // err
+	zapcore.DPanicLevel: 2, // crit
+	zapcore.PanicLevel:  1, // alert
+	zapcore.FatalLevel:  0, // emerg
+}
+
+// SyslogOptions is the option set for the syslog sink.
+type SyslogOptions struct {
+	// Network is the transport, Valid values are "udp", "tcp", "unix" and
+	// "unixgram". Empty means the local syslog daemon through its unix
+	// socket, e.g. /dev/log.
+	Network string `yaml:"network"`
+
+	// Address is host:port for udp and tcp, or the socket path for unix.
+	// It can be empty for the local syslog daemon.
+	Address string `yaml:"address"`
+
+	// Protocol is the message format, Valid values are "rfc5424" and
+	// "rfc3164", default is rfc5424.
+	Protocol string `yaml:"protocol"`
+
+	// Facility is the syslog facility such as "daemon" or "local0", default
+	// is user.
+	Facility string `yaml:"facility"`
+
+	// AppName identifies the program in the message header, default is the
+	// name of the executable.
+	AppName string `yaml:"app_name"`
+}
+
+func (opt SyslogOptions) validate(prefix string) error {
+	var errs error
+	switch opt.Network {
+	case "", "udp", "tcp", "unix", "unixgram":
+	default:
+		errs = multierr.Append(errs, fmt.Errorf("%ssyslog.network: unknown network %q", prefix, opt.Network))
+	}
+	if opt.Network != "" && opt.Address == "" {
+		errs = multierr.Append(errs, fmt.Errorf("%ssyslog.address: required for network %q", prefix, opt.Network))
+	}
+	switch opt.Protocol {
+	case "", SyslogRFC5424, SyslogRFC3164:
+	default:
+		errs = multierr.Append(errs, fmt.Errorf("%ssyslog.protocol: unknown protocol %q", prefix, opt.Protocol))
+	}
+	if _, ok := syslogFacilities[opt.Facility]; opt.Facility != "" && !ok {
+		errs = multierr.Append(errs, fmt.Errorf("%ssyslog.facility: unknown facility %q", prefix, opt.Facility))
+	}
+	return errs
+}
+
+// syslogCore 按照 syslog 协议为每条日志添加头部，由于 severity 取决于日志级别，无法使用 zapcore.ioCore
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out zapcore.WriteSyncer
+
+	rfc3164  bool
+	local    bool
+	octet    bool
+	newline  bool
+	facility int
+	hostname string
+	appName  string
+	pid      string
+}
+
+func newSyslogCore(opt SyslogOptions, enc zapcore.Encoder, out zapcore.WriteSyncer, enab zapcore.LevelEnabler) *syslogCore {
+	facility := opt.Facility
+	if facility == "" {
+		facility = defaultSyslogFacility
+	}
+	appName := opt.AppName
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	return &syslogCore{
+		LevelEnabler: enab,
+		enc:          enc,
+		out:          out,
+		rfc3164:      opt.Protocol == SyslogRFC3164,
+		local:        opt.Network == "",
+		octet:        opt.Network == "tcp",
+		newline:      opt.Network == "" || opt.Network == "unix",
+		facility:     syslogFacilities[facility],
+		hostname:     hostname,
+		appName:      appName,
+		pid:          strconv.Itoa(os.Getpid()),
+	}
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := bytes.TrimRight(buf.Bytes(), "\r\n")
+	_, err = c.out.Write(c.frame(ent, msg))
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// 与 ioCore 一致，进程可能即将退出
+		c.Sync()
+	}
+	return nil
+}
+
+func (c *syslogCore) Sync() error {
+	return c.out.Sync()
+}
+
+// frame 构造完整的 syslog 消息，tcp 使用 RFC 6587 的 octet counting 分帧
+func (c *syslogCore) frame(ent zapcore.Entry, msg []byte) []byte {
+	severity, ok := syslogSeverities[ent.Level]
+	if !ok {
+		severity = syslogSeverities[zapcore.InfoLevel]
+	}
+	pri := c.facility*8 + severity
+
+	var b bytes.Buffer
+	if c.rfc3164 {
+		// <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG，本地 socket 不需要 HOSTNAME
+		fmt.Fprintf(&b, "<%d>%s ", pri, ent.Time.Format(time.Stamp))
+		if !c.local {
+			b.WriteString(c.hostname)
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s[%s]: ", c.appName, c.pid)
+	} else {
+		// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+		fmt.Fprintf(&b, "<%d>1 %s %s %s %s - - ", pri,
+			ent.Time.Format("2006-01-02T15:04:05.000000Z07:00"), c.hostname, c.appName, c.pid)
+	}
+	b.Write(msg)
+
+	if c.octet {
+		return append([]byte(strconv.Itoa(b.Len())+" "), b.Bytes()...)
+	}
+	if c.newline {
+		// unix stream socket 使用换行分隔消息
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// newSyslogConn 返回到 syslog 的连接，Network 为空时连接本地的 syslog 守护进程
+func newSyslogConn(opt SyslogOptions) *redialConn {
+	if opt.Network == "" {
+		return newRedialConnFunc(func() (net.Conn, error) { return dialLocalSyslog(opt.Address) })
+	}
+	return newRedialConn(opt.Network, opt.Address)
+}
+
+// dialLocalSyslog 依次尝试本地的 unix socket，优先使用 datagram
+func dialLocalSyslog(address string) (net.Conn, error) {
+	addresses := syslogLocalAddresses
+	if address != "" {
+		addresses = []string{address}
+	}
+	var err error
+	for _, addr := range addresses {
+		for _, network := range []string{"unixgram", "unix"} {
+			var conn net.Conn
+			if conn, err = net.DialTimeout(network, addr, connDialTimeout); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unix syslog delivery error: %w", err)
+}
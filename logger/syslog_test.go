@@ -0,0 +1,231 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2017-2021 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+//
+
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSyslogFrame(t *testing.T) {
+	enc := NewLogfmtEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	ent := zapcore.Entry{
+		Level:   zapcore.WarnLevel,
+		Time:    time.Date(2021, 8, 1, 9, 5, 3, 123456000, time.UTC),
+		Message: "disk full",
+	}
+
+	c := newSyslogCore(SyslogOptions{Network: "tcp", Facility: "local0", AppName: "bkmonitorbeat"}, enc, nil, zapcore.DebugLevel)
+	c.hostname = "host-1"
+	c.pid = "42"
+	msg := `<132>1 2021-08-01T09:05:03.123456Z host-1 bkmonitorbeat 42 - - msg="disk full"`
+	assert.Equal(t, strconv.Itoa(len(msg))+" "+msg, string(c.frame(ent, []byte(`msg="disk full"`))))
+
+	c = newSyslogCore(SyslogOptions{Network: "udp", Protocol: SyslogRFC3164, AppName: "app"}, enc, nil, zapcore.DebugLevel)
+	c.hostname = "host-1"
+	c.pid = "42"
+	ent.Level = zapcore.ErrorLevel
+	assert.Equal(t, `<11>Aug  1 09:05:03 host-1 app[42]: failed`, string(c.frame(ent, []byte("failed"))))
+
+	c = newSyslogCore(SyslogOptions{Protocol: SyslogRFC3164, AppName: "app"}, enc, nil, zapcore.DebugLevel)
+	c.pid = "42"
+	assert.Equal(t, "<11>Aug  1 09:05:03 app[42]: failed\n", string(c.frame(ent, []byte("failed"))))
+}
+
+func TestLoggerSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	l := New(Options{Sinks: []SinkOptions{{
+		Type:   SinkSyslog,
+		Syslog: SyslogOptions{Network: "udp", Address: conn.LocalAddr().String(), AppName: "test"},
+	}}})
+	defer l.Close()
+	l.Errorw("report failed", "code", 500)
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	msg := string(buf[:n])
+	assert.Regexp(t, regexp.MustCompile(`^<11>1 \S+ \S+ test `+strconv.Itoa(os.Getpid())+` - - ts=.* level=error .* msg="report failed" code=500$`), msg)
+}
+
+func TestLoggerSyslogTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ln.Close()
+
+	received := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			// 读取一条 octet counting 分帧的消息后断开连接
+			var size int
+			if _, err := fmt.Fscanf(r, "%d ", &size); err == nil {
+				b := make([]byte, size)
+				if _, err := r.Read(b); err == nil {
+					received <- string(b)
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	l := New(Options{Sinks: []SinkOptions{{
+		Type:   SinkSyslog,
+		Syslog: SyslogOptions{Network: "tcp", Address: ln.Addr().String()},
+	}}})
+	defer l.Close()
+	w := l.root.load().res.closers[0].(*redialConn)
+	w.minBackoff = time.Millisecond
+
+	l.Info("first")
+	assert.Contains(t, <-received, "msg=first")
+
+	// 服务端已经断开，写入失败后重连
+	assert.Eventually(t, func() bool {
+		l.Info("second")
+		select {
+		case msg := <-received:
+			return strings.Contains(msg, "msg=second")
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestLoggerSyslogUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unixgram is not supported on windows")
+	}
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket("unixgram", addr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	l := New(Options{Sinks: []SinkOptions{{
+		Type:   SinkSyslog,
+		Syslog: SyslogOptions{Address: addr, Protocol: SyslogRFC3164, Facility: "daemon", AppName: "test"},
+	}}})
+	defer l.Close()
+	l.Warn("local")
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^<28>\w{3} [ \d]\d \d{2}:\d{2}:\d{2} test\[\d+\]: .* msg=local\n$`), string(buf[:n]))
+}
+
+func TestLoggerSyslogUnixStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket is not supported on windows")
+	}
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	ln, err := net.Listen("unix", addr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ln.Close()
+	lines := collectLines(ln)
+
+	// Network 为空时按实际建立的 unix 流式连接设置写入超时
+	w := newSyslogConn(SyslogOptions{Address: addr})
+	defer w.Close()
+	_, err = w.Write([]byte("local\n"))
+	assert.NoError(t, err)
+	assert.True(t, w.stream)
+	assert.Equal(t, "local", receiveLine(t, lines))
+}
+
+func TestRedialConnBackground(t *testing.T) {
+	var dials int32
+	release := make(chan struct{})
+	w := newRedialConnFunc(func() (net.Conn, error) {
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return nil, errConnUnavailable
+		}
+		// 之后的重连一直阻塞，直到 release 关闭
+		<-release
+		client, server := net.Pipe()
+		go func() {
+			buf := make([]byte, 1024)
+			for {
+				if _, err := server.Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+		return client, nil
+	})
+	w.minBackoff = time.Millisecond
+
+	// 第一次同步建立连接失败，之后在退避时间过后只在后台重连，写入不会等待
+	_, err := w.Write([]byte("first\n"))
+	assert.Equal(t, errConnUnavailable, err)
+	time.Sleep(10 * time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		_, err = w.Write([]byte("entry\n"))
+		assert.Equal(t, errConnUnavailable, err)
+	}
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&dials) == 2 }, 5*time.Second, time.Millisecond)
+	for i := 0; i < 100; i++ {
+		w.Write([]byte("entry\n"))
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&dials))
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		_, err := w.Write([]byte("entry\n"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, w.stream)
+	assert.NoError(t, w.Close())
+	_, err = w.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestOptionsValidateSyslog(t *testing.T) {
+	err := Options{Sinks: []SinkOptions{{
+		Type:   SinkSyslog,
+		Syslog: SyslogOptions{Network: "tcp", Protocol: "rfc9999", Facility: "local9"},
+	}}}.Validate()
+	assert.EqualError(t, err, `sinks[0].syslog.address: required for network "tcp"; `+
+		`sinks[0].syslog.protocol: unknown protocol "rfc9999"; `+
+		`sinks[0].syslog.facility: unknown facility "local9"`)
+}